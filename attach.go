@@ -3,20 +3,155 @@ package scurry
 import (
 	"bufio"
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+// Defaults for ScAttachConfig
 const (
-	CMD_Q_LEN = 100
+	CMD_Q_LEN    = 100
+	RESP_Q_LEN   = 100
+	MAX_RESP_LEN = 16 * 1024 * 1024
+
+	RECONNECT_BACKOFF     = time.Second
+	RECONNECT_MAX_BACKOFF = time.Minute
+)
+
+// Initial size of the buffer used to receive responses from scamper.
+// The buffer grows as needed, up to ScAttachConfig.MaxResponseLen.
+const rxBufLen = 64 * 1024
+
+// Configuration for an ScAttach
+type ScAttachConfig struct {
+	// Scamper control socket to attach to. Either host:port, or
+	// the path to a unix domain socket.
+	URL string
+	// If set, used to connect to scamper instead of dialing URL
+	// (e.g., to reach a remote scamper connected to a
+	// RemoteServer), in which case URL is only used to identify
+	// scamper in logs.
+	Dial func() (net.Conn, error) `json:"-"`
+
+	// Number of commands that may be queued for sending to
+	// scamper (default CMD_Q_LEN)
+	CommandQueueLen int
+	// Number of responses (results, acks and errors) from scamper
+	// that may be queued for the caller (default RESP_Q_LEN)
+	ResponseQueueLen int
+	// Maximum length, in bytes, of a single response from scamper
+	// (default MAX_RESP_LEN). Large traces can produce very long
+	// JSON objects.
+	MaxResponseLen int
+
+	// Don't try to reconnect if the connection to scamper drops
+	DisableReconnect bool
+	// How long to wait before trying to reconnect (default
+	// RECONNECT_BACKOFF). The wait doubles after each failed
+	// attempt, up to ReconnectMaxBackoff (default
+	// RECONNECT_MAX_BACKOFF).
+	ReconnectBackoff    time.Duration
+	ReconnectMaxBackoff time.Duration
+	// Number of consecutive failed attempts to reconnect after
+	// which to give up. Zero means no limit.
+	MaxReconnectAttempts int
+}
+
+// Checks that the config is usable. Zero values are replaced with
+// defaults, so only the URL is required.
+func (cfg ScAttachConfig) Validate() error {
+	if cfg.URL == "" {
+		return fmt.Errorf("scamper URL must be set")
+	}
+	return cfg.validateOpts()
+}
+
+// Checks everything but the URL
+func (cfg ScAttachConfig) validateOpts() error {
+	if cfg.CommandQueueLen < 0 {
+		return fmt.Errorf("command queue length must not be negative")
+	}
+	if cfg.ResponseQueueLen < 0 {
+		return fmt.Errorf("response queue length must not be negative")
+	}
+	if cfg.MaxResponseLen < 0 {
+		return fmt.Errorf("max response length must not be negative")
+	}
+	if cfg.ReconnectBackoff < 0 || cfg.ReconnectMaxBackoff < 0 {
+		return fmt.Errorf("reconnect backoff must not be negative")
+	}
+	if cfg.MaxReconnectAttempts < 0 {
+		return fmt.Errorf("max reconnect attempts must not be negative")
+	}
+	return nil
+}
+
+func (cfg ScAttachConfig) withDefaults() ScAttachConfig {
+	if cfg.CommandQueueLen == 0 {
+		cfg.CommandQueueLen = CMD_Q_LEN
+	}
+	if cfg.ResponseQueueLen == 0 {
+		cfg.ResponseQueueLen = RESP_Q_LEN
+	}
+	if cfg.MaxResponseLen == 0 {
+		cfg.MaxResponseLen = MAX_RESP_LEN
+	}
+	if cfg.ReconnectBackoff == 0 {
+		cfg.ReconnectBackoff = RECONNECT_BACKOFF
+	}
+	if cfg.ReconnectMaxBackoff == 0 {
+		cfg.ReconnectMaxBackoff = RECONNECT_MAX_BACKOFF
+	}
+	return cfg
+}
+
+// Scamper's acceptance of a measurement command
+type ScAck struct {
+	Cmd    string // the command that was accepted
+	TaskId uint64 // scamper's ID for the task
+}
+
+// Scamper's rejection of a command
+type ScError struct {
+	Cmd string // the command that was rejected
+	Err string // scamper's error message
+}
+
+func (e ScError) Error() string {
+	return fmt.Sprintf("scamper rejected command '%s': %s", e.Cmd, e.Err)
+}
+
+//go:generate enumer -type=ConnState -json -text -linecomment
+type ConnState uint8
+
+const (
+	CONN_CONNECTED    ConnState = iota // connected
+	CONN_DISCONNECTED                  // disconnected
+	// Gave up trying to reconnect. No further responses will be
+	// received from scamper.
+	CONN_CLOSED // closed
 )
 
+// A change in the state of the connection to scamper
+type ConnEvent struct {
+	State ConnState
+	Err   error // why the connection dropped
+	// Commands that had been sent to scamper, but not yet
+	// accepted or rejected, when the connection dropped. Tasks
+	// that scamper had already accepted are lost too, since their
+	// results would have been returned over the old connection.
+	Lost []string
+}
+
 // Simple wrapper around a TCP connection "attached" to a scamper daemon
 type ScAttach struct {
 	log    Logger
-	moreCh chan struct{} // number of unused "MORE"s from scamper
+	cfg    ScAttachConfig
+	moreCh chan uint64 // unused "MORE"s from scamper (by connection)
 
 	txWorkerCancel context.CancelFunc
 	txWorkerWg     *sync.WaitGroup
@@ -24,34 +159,56 @@ type ScAttach struct {
 	rxWorkerCancel context.CancelFunc
 	rxWorkerWg     *sync.WaitGroup
 
-	cmdQ  chan string // queue of commands to send to scamper
-	dataQ chan string // queue of data responses received from scamper
-	errQ  chan string // queue of error responses received from scamper
+	cmdQ   chan string    // queue of commands to send to scamper
+	ackQ   chan ScAck     // queue of commands accepted by scamper
+	dataQ  chan string    // queue of data responses received from scamper
+	errQ   chan ScError   // queue of commands rejected by scamper
+	eventQ chan ConnEvent // queue of connection state changes
+
+	// commands sent to scamper that are awaiting an OK/ERR
+	// response. scamper responds to commands in order.
+	sent []string
+	txMu *sync.Mutex // protects sent, conn, connGen and txBuf
 
-	conn  net.Conn
-	txBuf *bufio.Writer
+	conn    net.Conn // nil while disconnected
+	connGen uint64   // incremented each time we (re)connect
+	txBuf   *bufio.Writer
 }
 
-func NewScAttach(log Logger, url string) (*ScAttach, error) {
+var errNotConnected = fmt.Errorf("not connected to scamper")
+
+func NewScAttach(log Logger, cfg ScAttachConfig) (*ScAttach, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
 	txWorkerCtx, txWorkerCancel := context.WithCancel(context.Background())
 	rxWorkerCtx, rxWorkerCancel := context.WithCancel(context.Background())
 
 	a := &ScAttach{
 		log:    initLogger(log, "sc-attach"),
-		moreCh: make(chan struct{}, CMD_Q_LEN),
+		cfg:    cfg,
+		moreCh: make(chan uint64, cfg.CommandQueueLen),
 
 		txWorkerCancel: txWorkerCancel,
 		txWorkerWg:     &sync.WaitGroup{},
 		rxWorkerCancel: rxWorkerCancel,
 		rxWorkerWg:     &sync.WaitGroup{},
 
-		cmdQ:  make(chan string, CMD_Q_LEN),
-		dataQ: make(chan string, CMD_Q_LEN),
-		errQ:  make(chan string, CMD_Q_LEN),
+		cmdQ:   make(chan string, cfg.CommandQueueLen),
+		ackQ:   make(chan ScAck, cfg.ResponseQueueLen),
+		dataQ:  make(chan string, cfg.ResponseQueueLen),
+		errQ:   make(chan ScError, cfg.ResponseQueueLen),
+		eventQ: make(chan ConnEvent, cfg.ResponseQueueLen),
+
+		txMu: &sync.Mutex{},
 	}
 
 	// connect to scamper
-	if err := a.initConnection(url); err != nil {
+	if err := a.connect(); err != nil {
+		txWorkerCancel()
+		rxWorkerCancel()
 		return nil, err
 	}
 
@@ -72,10 +229,38 @@ func (a *ScAttach) ResultQueue() chan string {
 	return a.dataQ
 }
 
-func (a *ScAttach) ErrorQueue() chan string {
+// Commands that have been rejected by scamper, along with scamper's
+// error message.
+func (a *ScAttach) ErrorQueue() chan ScError {
 	return a.errQ
 }
 
+// Commands that have been accepted by scamper, along with the ID that
+// scamper assigned to the resulting task. This can be used to halt
+// the task.
+func (a *ScAttach) AckQueue() chan ScAck {
+	return a.ackQ
+}
+
+// Changes in the state of the connection to scamper. When the
+// connection drops, ScAttach reconnects (unless configured not to)
+// and continues sending commands from the CommandQueue, but commands
+// that were already sent are lost.
+func (a *ScAttach) EventQueue() chan ConnEvent {
+	return a.eventQ
+}
+
+// Asks scamper to halt the task with the given (scamper-assigned) ID.
+// The halt is sent immediately, bypassing the CommandQueue.
+func (a *ScAttach) Halt(taskId uint64) {
+	if err := a.sendCmd(fmt.Sprintf("halt %d", taskId), 0); err != nil {
+		a.log.Warn().
+			Err(err).
+			Uint64("task-id", taskId).
+			Msgf("Failed to halt scamper task")
+	}
+}
+
 func (a *ScAttach) Close() {
 	// cancel our tx worker and wait for it to be done
 	a.txWorkerCancel()
@@ -84,54 +269,179 @@ func (a *ScAttach) Close() {
 	a.rxWorkerCancel()
 	a.rxWorkerWg.Wait()
 	// and now shut down our connection to scamper
-	a.conn.Close()
+	a.txMu.Lock()
+	if a.conn != nil {
+		a.conn.Close()
+	}
+	a.txMu.Unlock()
 }
 
 // private methods
 
-func (a *ScAttach) initConnection(url string) error {
+func (a *ScAttach) dial() (net.Conn, error) {
+	if a.cfg.Dial != nil {
+		return a.cfg.Dial()
+	}
 	// TODO: better unix socket detection
-	var conn net.Conn
-	var err error
-	if strings.Contains(":", url) {
-		conn, err = net.Dial("tcp", url)
-	} else {
-		conn, err = net.Dial("unix", url)
+	if strings.Contains(a.cfg.URL, ":") {
+		return net.Dial("tcp", a.cfg.URL)
 	}
+	return net.Dial("unix", a.cfg.URL)
+}
+
+// Connects (or reconnects) to scamper and attaches to it
+func (a *ScAttach) connect() error {
+	conn, err := a.dial()
 	if err != nil {
 		return err
 	}
+	a.txMu.Lock()
 	a.conn = conn
-
+	a.connGen++
+	a.sent = nil
 	// create buffer for tx
 	a.txBuf = bufio.NewWriter(a.conn)
+	a.txMu.Unlock()
+
 	// send our attach command
-	a.sendCmd("attach format json")
+	return a.sendCmd("attach format json", 0)
+}
 
-	return nil
+// Closes a dropped connection, and returns the commands that scamper
+// had not responded to.
+func (a *ScAttach) disconnect() []string {
+	a.txMu.Lock()
+	defer a.txMu.Unlock()
+	if a.conn != nil {
+		a.conn.Close()
+	}
+	a.conn = nil
+	a.txBuf = nil
+	lost := []string{}
+	for _, cmd := range a.sent {
+		// we don't care about our own commands
+		if cmd != "attach format json" && !strings.HasPrefix(cmd, "halt ") {
+			lost = append(lost, cmd)
+		}
+	}
+	a.sent = nil
+	// and any MOREs we had are no longer valid
+	for len(a.moreCh) > 0 {
+		<-a.moreCh
+	}
+	return lost
+}
+
+// Tries to reconnect to scamper, backing off exponentially between
+// attempts.
+func (a *ScAttach) reconnect(ctx context.Context) error {
+	backoff := a.cfg.ReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		err := a.connect()
+		if err == nil {
+			a.log.Info().
+				Int("attempt", attempt).
+				Msgf("Reconnected to scamper")
+			return nil
+		}
+		a.log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Msgf("Failed to reconnect to scamper")
+		if a.cfg.MaxReconnectAttempts > 0 &&
+			attempt >= a.cfg.MaxReconnectAttempts {
+			return fmt.Errorf("gave up reconnecting to scamper after %d attempts: %v",
+				attempt, err)
+		}
+		backoff *= 2
+		if backoff > a.cfg.ReconnectMaxBackoff {
+			backoff = a.cfg.ReconnectMaxBackoff
+		}
+	}
 }
 
-func (a *ScAttach) sendCmd(cmd string) {
-	// this assumes that there is MORE available
+// Sends a command to scamper. If gen is non-zero, the command is only
+// sent if we are still on that connection.
+func (a *ScAttach) sendCmd(cmd string, gen uint64) error {
 	a.log.Debug().
 		Str("command", cmd).
 		Msgf("Sending command to scamper")
+	a.txMu.Lock()
+	defer a.txMu.Unlock()
+	if a.conn == nil || (gen != 0 && gen != a.connGen) {
+		return errNotConnected
+	}
+	// if the write fails, the connection has dropped and the
+	// command will be reported as lost
+	a.sent = append(a.sent, cmd)
 	a.txBuf.WriteString(cmd)
 	a.txBuf.WriteString("\n")
-	a.txBuf.Flush()
+	return a.txBuf.Flush()
+}
+
+func (a *ScAttach) currentGen() uint64 {
+	a.txMu.Lock()
+	defer a.txMu.Unlock()
+	return a.connGen
+}
+
+func (a *ScAttach) currentConn() net.Conn {
+	a.txMu.Lock()
+	defer a.txMu.Unlock()
+	return a.conn
+}
+
+// Sends an event, unless we're shutting down
+func (a *ScAttach) emit(ctx context.Context, ev ConnEvent) {
+	select {
+	case a.eventQ <- ev:
+	case <-ctx.Done():
+	}
+}
+
+// Pops the oldest command that scamper has not yet responded to
+func (a *ScAttach) popSent() string {
+	a.txMu.Lock()
+	defer a.txMu.Unlock()
+	if len(a.sent) == 0 {
+		a.log.Warn().Msgf("Got response from scamper with no command outstanding")
+		return ""
+	}
+	cmd := a.sent[0]
+	a.sent = a.sent[1:]
+	return cmd
 }
 
 func (a *ScAttach) handleResponse(resp string) {
 	if strings.HasPrefix(resp, "OK") {
-		// ignore these, they're expected
+		cmd := a.popSent()
 		a.log.Debug().
+			Str("command", cmd).
+			Str("response", resp).
 			Msgf("Got OK from scamper")
+		// measurement commands are acknowledged with "OK id-N"
+		if !strings.HasPrefix(resp, "OK id-") {
+			return
+		}
+		taskId, err := strconv.ParseUint(resp[6:], 10, 64)
+		if err != nil {
+			a.log.Warn().
+				Str("response", resp).
+				Msgf("Failed to parse task ID from scamper")
+			return
+		}
+		a.ackQ <- ScAck{Cmd: cmd, TaskId: taskId}
 		return
 	}
 
 	if resp == "MORE" {
 		select {
-		case a.moreCh <- struct{}{}:
+		case a.moreCh <- a.currentGen():
 			// cool
 			a.log.Debug().
 				Int("mores", len(a.moreCh)).
@@ -154,7 +464,17 @@ func (a *ScAttach) handleResponse(resp string) {
 	}
 
 	if strings.HasPrefix(resp, "ERR") {
-		a.errQ <- resp[4:]
+		cmd := a.popSent()
+		if strings.HasPrefix(cmd, "halt ") {
+			// most likely the task finished before the halt
+			// arrived
+			a.log.Debug().
+				Str("command", cmd).
+				Str("error", resp[4:]).
+				Msgf("Scamper failed to halt task")
+			return
+		}
+		a.errQ <- ScError{Cmd: cmd, Err: resp[4:]}
 		return
 	}
 
@@ -166,31 +486,81 @@ func (a *ScAttach) rxWorker(ctx context.Context) {
 	defer func() {
 		a.rxWorkerWg.Done() // signal to close that we're done
 		close(a.dataQ)
+		close(a.ackQ)
 		close(a.errQ)
+		close(a.eventQ)
 	}()
 
+	for {
+		err := a.serviceConn(ctx, a.currentConn())
+		if ctx.Err() != nil {
+			// canceled, just give up
+			return
+		}
+
+		// our connection to scamper has dropped
+		lost := a.disconnect()
+		a.log.Error().
+			Err(err).
+			Int("lost", len(lost)).
+			Msgf("Lost connection to scamper")
+		a.emit(ctx, ConnEvent{State: CONN_DISCONNECTED, Err: err, Lost: lost})
+
+		if a.cfg.DisableReconnect {
+			a.emit(ctx, ConnEvent{State: CONN_CLOSED, Err: err})
+			return
+		}
+		if err := a.reconnect(ctx); err != nil {
+			if ctx.Err() == nil {
+				a.log.Error().
+					Err(err).
+					Msgf("Giving up on scamper")
+				a.emit(ctx, ConnEvent{State: CONN_CLOSED, Err: err})
+			}
+			return
+		}
+		a.emit(ctx, ConnEvent{State: CONN_CONNECTED})
+	}
+}
+
+// Handles responses from scamper until the connection drops (in which
+// case the reason is returned), or ctx is done.
+func (a *ScAttach) serviceConn(ctx context.Context, conn net.Conn) error {
 	// start up a goroutine to chunk rx into lines
-	rxChan := make(chan string, CMD_Q_LEN)
-	go a.scamperRx(rxChan)
+	rxChan := make(chan string, a.cfg.ResponseQueueLen)
+	rxErr := make(chan error, 1)
+	go a.scamperRx(conn, rxChan, rxErr)
 
 	for {
 		select {
-		case resp := <-rxChan:
+		case resp, ok := <-rxChan:
+			if !ok {
+				err := <-rxErr
+				if err == nil {
+					err = io.EOF
+				}
+				return err
+			}
 			a.handleResponse(resp)
 
 		case <-ctx.Done():
-			// canceled, just give up
-			return
+			return nil
 		}
 	}
 }
 
-func (a *ScAttach) scamperRx(outCh chan string) {
-	rxBuf := bufio.NewReader(a.conn)
+func (a *ScAttach) scamperRx(conn net.Conn, outCh chan string, errCh chan error) {
+	rxBuf := bufio.NewReader(conn)
 	scanner := bufio.NewScanner(rxBuf)
+	bufLen := rxBufLen
+	if a.cfg.MaxResponseLen < bufLen {
+		bufLen = a.cfg.MaxResponseLen
+	}
+	scanner.Buffer(make([]byte, bufLen), a.cfg.MaxResponseLen)
 	for scanner.Scan() {
 		outCh <- scanner.Text()
 	}
+	errCh <- scanner.Err()
 	close(outCh)
 	a.log.Debug().Msgf("Scamper rx loop ending")
 }
@@ -204,10 +574,23 @@ func (a *ScAttach) txWorker(ctx context.Context) {
 	for {
 		select {
 		case cmd := <-a.cmdQ:
-			// we have a measurement, wait until scamper wants it
-			<-a.moreCh
-			// alright, good to go, fire it off
-			a.sendCmd(cmd)
+			if strings.ContainsAny(cmd, "\r\n") {
+				// scamper would read this as several
+				// commands, which would throw off the
+				// pairing of responses with commands
+				a.log.Error().
+					Str("command", cmd).
+					Msgf("Refusing to send multi-line command")
+				select {
+				case a.errQ <- ScError{Cmd: cmd, Err: "command must be a single line"}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			if !a.txCmd(ctx, cmd) {
+				return
+			}
 
 		case <-ctx.Done():
 			a.log.Debug().Msgf("TX worker shutting down")
@@ -215,3 +598,22 @@ func (a *ScAttach) txWorker(ctx context.Context) {
 		}
 	}
 }
+
+// Waits until scamper wants a command, and then sends it. If we're
+// disconnected, we hang on to the command until we reconnect. Returns
+// false if ctx is done first.
+func (a *ScAttach) txCmd(ctx context.Context, cmd string) bool {
+	for {
+		select {
+		case gen := <-a.moreCh:
+			// alright, good to go, fire it off
+			if err := a.sendCmd(cmd, gen); err != errNotConnected {
+				return true
+			}
+
+		case <-ctx.Done():
+			a.log.Debug().Msgf("TX worker shutting down")
+			return false
+		}
+	}
+}
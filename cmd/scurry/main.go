@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -25,15 +26,33 @@ type TraceCmd struct {
 
 type ScurryCLI struct {
 	// measurement commands
-	Ping  measurement.Ping  `cmd help:"Ping measurements"`
-	Trace measurement.Trace `cmd help:"Traceroute measurements"`
+	Ping    measurement.Ping    `cmd:"" help:"Ping measurements"`
+	Trace   measurement.Trace   `cmd:"" help:"Traceroute measurements"`
+	Tracelb measurement.Tracelb `cmd:"" help:"MDA load-balancer traceroute measurements"`
+	Dealias measurement.Dealias `cmd:"" help:"Alias resolution measurements (targets are whitespace-separated address sets)"`
+	Sting   measurement.Sting   `cmd:"" help:"One-way TCP loss measurements"`
+	Tbit    measurement.Tbit    `cmd:"" help:"TCP behaviour inference measurements"`
+	Sniff   measurement.Sniff   `cmd:"" help:"Packet capture (targets are capture expressions, e.g., 'icmp[icmpid] == 1234')"`
+	Host    measurement.Host    `cmd:"" help:"DNS measurements (targets are names to query)"`
+	Http    measurement.Http    `cmd:"" help:"HTTP measurements (targets are server addresses)"`
 
 	// global measurement config
-	Target []string `required short:"t" help:"IP to execute measurements towards"`
+	Target       []string      `required:"" short:"t" help:"IP to execute measurements towards"`
+	TaskTimeout  time.Duration `help:"Maximum length of time to wait for each measurement to complete (0 for no limit)" default:"0"`
+	Linger       time.Duration `help:"Maximum length of time to wait for outstanding measurements once all have been queued (0 for no limit)" default:"60s"`
+	MaxPerTarget int           `help:"Maximum number of concurrent measurements towards a single target (0 for no limit)" default:"0"`
 	// TODO: TargetFile
 	//
 	// scamper connection info
-	ScamperURL string `required short:"s" help:"URL to connect to scamper on (host:port or unix domain socket)"`
+	ScamperURL []string `short:"s" help:"URL to connect to scamper on (host:port or unix domain socket). May be given several times, as name=url, to run measurements from several vantage points"`
+	Vantage    []string `help:"Names of the vantage points to run measurements from (default all)"`
+	// or, scamper process config
+	ScamperBin string `help:"Path to a scamper binary to run, rather than connecting to an existing scamper"`
+	PPS        int    `help:"Packets per second that scamper may send (with --scamper-bin)"`
+	Window     int    `help:"Number of tasks that scamper may probe concurrently (with --scamper-bin)"`
+
+	// output config
+	OutputFormat measurement.JsonFormat `help:"Format to output results in (task, task-raw, or scamper)" default:"task"`
 
 	// misc flags
 	LogLevel string `help:"Log level" default:"info"`
@@ -95,16 +114,82 @@ func initTask(cmd string, cfg ScurryCLI) (measurement.Task, error) {
 
 	case measurement.TYPE_TRACE:
 		task.Options.Trace = cfg.Trace
+
+	case measurement.TYPE_TRACELB:
+		task.Options.Tracelb = cfg.Tracelb
+
+	case measurement.TYPE_DEALIAS:
+		task.Options.Dealias = cfg.Dealias
+
+	case measurement.TYPE_STING:
+		task.Options.Sting = cfg.Sting
+
+	case measurement.TYPE_TBIT:
+		task.Options.Tbit = cfg.Tbit
+
+	case measurement.TYPE_SNIFF:
+		task.Options.Sniff = cfg.Sniff
+
+	case measurement.TYPE_HOST:
+		task.Options.Host = cfg.Host
+
+	case measurement.TYPE_HTTP:
+		task.Options.Http = cfg.Http
 	}
 
 	return task, nil
 }
 
+// The parts of scurry.Controller (and scurry.MultiController) that we
+// use
+type taskRunner interface {
+	TaskQueue() chan measurement.Task
+	ResultQueue() chan measurement.Task
+	Drain(ctx context.Context) error
+	Close()
+}
+
+// Returns true if there are several (or named) vantage points
+func isMultiVantage(cfg ScurryCLI) bool {
+	return len(cfg.ScamperURL) > 1 ||
+		(len(cfg.ScamperURL) == 1 && strings.Contains(cfg.ScamperURL[0], "="))
+}
+
+// Creates a Controller, or a MultiController if there are several (or
+// named) vantage points
+func initRunner(log zerolog.Logger, cfg ScurryCLI) (taskRunner, error) {
+	ctrlCfg := scurry.ControllerConfig{
+		DefaultTimeout: cfg.TaskTimeout,
+		MaxPerTarget:   cfg.MaxPerTarget,
+	}
+	if cfg.ScamperBin != "" {
+		ctrlCfg.Scamper = &scurry.ScamperProcessConfig{
+			Binary: cfg.ScamperBin,
+			PPS:    cfg.PPS,
+			Window: cfg.Window,
+		}
+		return scurry.NewController(log, ctrlCfg)
+	}
+	if !isMultiVantage(cfg) {
+		ctrlCfg.ScamperURL = cfg.ScamperURL[0]
+		return scurry.NewController(log, ctrlCfg)
+	}
+
+	multiCfg := scurry.MultiControllerConfig{}
+	for _, url := range cfg.ScamperURL {
+		vp := scurry.ParseVantagePoint(url)
+		vp.Controller.DefaultTimeout = ctrlCfg.DefaultTimeout
+		vp.Controller.MaxPerTarget = ctrlCfg.MaxPerTarget
+		multiCfg.VantagePoints = append(multiCfg.VantagePoints, vp)
+	}
+	return scurry.NewMultiController(log, multiCfg)
+}
+
 // TODO: move this stuff into the scurry package? Some kind of
 // QueueTargets(ctx, meas, targets) method that does this work. How to
 // keep it async?
 func queueTasks(ctx context.Context, log zerolog.Logger, wg *sync.WaitGroup,
-	ctrl *scurry.Controller, task measurement.Task, cfg ScurryCLI) {
+	ctrl taskRunner, task measurement.Task, cfg ScurryCLI) {
 	defer wg.Done()
 
 	mCh := ctrl.TaskQueue()
@@ -120,24 +205,39 @@ func queueTasks(ctx context.Context, log zerolog.Logger, wg *sync.WaitGroup,
 }
 
 func recvResults(ctx context.Context, log zerolog.Logger, wg *sync.WaitGroup,
-	ctrl *scurry.Controller) {
+	ctrl taskRunner, cfg ScurryCLI) {
 	log.Debug().Msgf("Result receiver online")
 	defer wg.Done()
 
 	cnt := uint64(0)
+	failed := uint64(0)
 	q := ctrl.ResultQueue()
-	eoq := measurement.Task{}
 	for {
 		select {
-		case result := <-q:
-			if result == eoq {
+		case result, ok := <-q:
+			if !ok {
 				log.Info().
 					Uint64("total", cnt).
+					Uint64("failed", failed).
 					Msgf("Finished receiving results")
 				return
 			}
 			cnt++
-			j, err := result.AsJson()
+			if result.Failed() {
+				failed++
+				log.Warn().
+					Str("target", result.Target).
+					Str("vantage", result.Vantage).
+					Str("status", result.Status.String()).
+					Str("error", result.Error).
+					Msgf("Measurement failed")
+				if result.Result == nil &&
+					cfg.OutputFormat == measurement.JSON_SCAMPER {
+					// nothing to output
+					continue
+				}
+			}
+			j, err := result.AsJsonFormat(cfg.OutputFormat)
 			if err != nil {
 				log.Error().
 					Err(err).
@@ -157,6 +257,12 @@ func main() {
 	var cliCfg ScurryCLI
 	k := kong.Parse(&cliCfg)
 	k.Validate()
+	if (len(cliCfg.ScamperURL) == 0) == (cliCfg.ScamperBin == "") {
+		k.Fatalf("exactly one of --scamper-url or --scamper-bin must be given")
+	}
+	if len(cliCfg.Vantage) > 0 && !isMultiVantage(cliCfg) {
+		k.Fatalf("--vantage requires named vantage points (--scamper-url name=url)")
+	}
 
 	// Set up context, logger, and signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -169,13 +275,16 @@ func main() {
 	// We'll just modify the `Target` field.
 	task, err := initTask(k.Command(), cliCfg)
 	k.FatalIfErrorf(err)
+	task.Vantages = cliCfg.Vantage
+	for _, target := range cliCfg.Target {
+		task.Target = target
+		if err := task.Validate(); err != nil {
+			k.Fatalf("invalid measurement towards %s: %v", target, err)
+		}
+	}
 
 	// Create the scurry Controller
-	ctrl, err := scurry.NewController(log,
-		scurry.ControllerConfig{
-			ScamperURL: cliCfg.ScamperURL,
-		},
-	)
+	ctrl, err := initRunner(log, cliCfg)
 	k.FatalIfErrorf(err)
 	defer ctrl.Close()
 
@@ -195,15 +304,25 @@ func main() {
 	// And another to retrieve the responses
 	resWg := &sync.WaitGroup{}
 	resWg.Add(1)
-	go recvResults(ctx, log, resWg, ctrl)
+	go recvResults(ctx, log, resWg, ctrl, cliCfg)
 
 	// Wait until we have queued all our tasks
 	qWg.Wait()
 
 	// Tell the controller that we're done queueing things. This
-	// will block until all of the tasks we queued have been
-	// handed off to scamper.
-	ctrl.Drain()
+	// will block until all of the tasks we queued have finished,
+	// or until we give up waiting for them.
+	drainCtx := ctx
+	if cliCfg.Linger > 0 {
+		var drainCancel context.CancelFunc
+		drainCtx, drainCancel = context.WithTimeout(ctx, cliCfg.Linger)
+		defer drainCancel()
+	}
+	if err := ctrl.Drain(drainCtx); err != nil {
+		log.Warn().
+			Err(err).
+			Msgf("Gave up waiting for some measurements")
+	}
 
 	// Wait until we've received all the results (the Controller
 	// will signal this by closing the result channel).
@@ -0,0 +1,80 @@
+// Code generated by "enumer -type=ConnState -json -text -linecomment"; DO NOT EDIT.
+
+package scurry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _ConnStateName = "connecteddisconnectedclosed"
+
+var _ConnStateIndex = [...]uint8{0, 9, 21, 27}
+
+func (i ConnState) String() string {
+	if i >= ConnState(len(_ConnStateIndex)-1) {
+		return fmt.Sprintf("ConnState(%d)", i)
+	}
+	return _ConnStateName[_ConnStateIndex[i]:_ConnStateIndex[i+1]]
+}
+
+var _ConnStateValues = []ConnState{0, 1, 2}
+
+var _ConnStateNameToValueMap = map[string]ConnState{
+	_ConnStateName[0:9]:   0,
+	_ConnStateName[9:21]:  1,
+	_ConnStateName[21:27]: 2,
+}
+
+// ConnStateString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func ConnStateString(s string) (ConnState, error) {
+	if val, ok := _ConnStateNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to ConnState values", s)
+}
+
+// ConnStateValues returns all values of the enum
+func ConnStateValues() []ConnState {
+	return _ConnStateValues
+}
+
+// IsAConnState returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i ConnState) IsAConnState() bool {
+	for _, v := range _ConnStateValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for ConnState
+func (i ConnState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for ConnState
+func (i *ConnState) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("ConnState should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = ConnStateString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for ConnState
+func (i ConnState) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for ConnState
+func (i *ConnState) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = ConnStateString(string(text))
+	return err
+}
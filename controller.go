@@ -2,33 +2,168 @@ package scurry
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/alistairking/scurry/measurement"
 	"github.com/rs/zerolog"
 )
 
+// Defaults for ControllerConfig
 const (
-	// TODO: make this configurable
-	SEND_Q_LEN      = 100
-	RECV_Q_LEN      = 100
-	SHUTDOWN_LINGER = time.Second * 60
+	SEND_Q_LEN = 100
+	RECV_Q_LEN = 100
+)
+
+const (
+	// how often to check for tasks that have passed their deadline
+	TIMEOUT_CHECK_INTERVAL = time.Millisecond * 100
+)
+
+var (
+	ErrControllerDraining = errors.New("controller is draining")
+	ErrTaskAbandoned      = errors.New("gave up waiting for result from scamper")
+	ErrTaskCancelled      = errors.New("task cancelled")
+	ErrTaskTimedOut       = errors.New("task timed out")
+	ErrConnectionLost     = errors.New("lost connection to scamper")
+	ErrInvalidTask        = errors.New("invalid task")
+	ErrUnknownTask        = errors.New("no outstanding task with this UserId")
+)
+
+// What to do with tasks that were lost when the connection to scamper
+// dropped
+//
+//go:generate enumer -type=ReplayPolicy -json -text -linecomment
+type ReplayPolicy uint8
+
+const (
+	// Resend all lost tasks once reconnected
+	REPLAY_ALL ReplayPolicy = iota // all
+	// Only resend tasks that scamper had not yet accepted (and so
+	// had not started probing)
+	REPLAY_UNACCEPTED // unaccepted
+	// Return lost tasks as abandoned
+	REPLAY_NONE // none
 )
 
 type ControllerConfig struct {
+	// Scamper control socket to attach to. Shorthand for
+	// Attach.URL.
 	ScamperURL string
+	// Configuration for the connection to scamper
+	Attach ScAttachConfig
+	// If set, the Controller starts (and supervises) its own
+	// scamper process, rather than connecting to an existing one,
+	// in which case ScamperURL and Attach.URL must not be set.
+	Scamper *ScamperProcessConfig
+
+	// Number of tasks that may be queued on the TaskQueue
+	// (default SEND_Q_LEN)
+	TaskQueueLen int
+	// Number of finished tasks that may be queued on the
+	// ResultQueue (default RECV_Q_LEN)
+	ResultQueueLen int
+
+	// Timeout to apply to tasks that do not set their own Timeout
+	// or Deadline. Zero means no timeout.
+	DefaultTimeout time.Duration
+
+	// Policy for retrying failed tasks. By default, tasks are not
+	// retried.
+	Retry RetryPolicy
+
+	// What to do with tasks that were lost when the connection to
+	// scamper dropped. By default, they are all resent (with a
+	// fresh UserId) once reconnected.
+	Replay ReplayPolicy
+
+	// Maximum number of tasks towards a single target (see
+	// Task.Target) that may be outstanding at once. Since scamper
+	// only probes one target at a time, further tasks towards a
+	// busy target are held back by the Controller (see Waiting)
+	// until an earlier one finishes, rather than tying up
+	// scamper's window. Zero means no limit.
+	MaxPerTarget int
+}
+
+// Checks that the config is usable. Zero values are replaced with
+// defaults, so only the scamper URL is required.
+func (cfg ControllerConfig) Validate() error {
+	if cfg.ScamperURL != "" && cfg.Attach.URL != "" &&
+		cfg.ScamperURL != cfg.Attach.URL {
+		return fmt.Errorf("ScamperURL and Attach.URL must match if both are set")
+	}
+	if cfg.TaskQueueLen < 0 {
+		return fmt.Errorf("task queue length must not be negative")
+	}
+	if cfg.ResultQueueLen < 0 {
+		return fmt.Errorf("result queue length must not be negative")
+	}
+	if !cfg.Replay.IsAReplayPolicy() {
+		return fmt.Errorf("invalid replay policy: %s", cfg.Replay)
+	}
+	if cfg.DefaultTimeout < 0 {
+		return fmt.Errorf("default timeout must not be negative")
+	}
+	if cfg.MaxPerTarget < 0 {
+		return fmt.Errorf("max tasks per target must not be negative")
+	}
+	if err := cfg.Retry.Validate(); err != nil {
+		return err
+	}
+	attachCfg := cfg.withDefaults().Attach
+	if cfg.Scamper != nil {
+		if attachCfg.URL != "" {
+			return fmt.Errorf("scamper URL must not be set when running scamper")
+		}
+		if err := cfg.Scamper.Validate(); err != nil {
+			return err
+		}
+		// the URL is that of the scamper process
+		return attachCfg.validateOpts()
+	}
+	return attachCfg.Validate()
+}
+
+func (cfg ControllerConfig) withDefaults() ControllerConfig {
+	if cfg.Attach.URL == "" {
+		cfg.Attach.URL = cfg.ScamperURL
+	}
+	cfg.ScamperURL = cfg.Attach.URL
+	if cfg.TaskQueueLen == 0 {
+		cfg.TaskQueueLen = SEND_Q_LEN
+	}
+	if cfg.ResultQueueLen == 0 {
+		cfg.ResultQueueLen = RECV_Q_LEN
+	}
+	return cfg
 }
 
 // Simple scamper control socket client
 type Controller struct {
 	log         Logger
 	cfg         ControllerConfig
+	scamper     *ScamperProcess // if we're running scamper ourselves
 	attach      *ScAttach
 	outstanding map[uint64]measurement.Task
+	handles     map[uint64]*TaskHandle // for tasks sent by Submit/Do
+	cmdIds      map[string]uint64      // command => UserId, until acked
+	scIds       map[uint64]uint64      // UserId => scamper task ID
+	cancelled   map[uint64]uint64      // UserId => scamper task ID (or 0)
+	deadlines   map[uint64]time.Time   // UserId => deadline, once sent
+	retryAt     map[uint64]time.Time   // UserId => when to send a retry
+	inFlight    map[string]int         // target => tasks not held back
+	waiting     map[string][]uint64    // target => held-back UserIds
+	held        map[uint64]bool        // UserIds of held-back tasks
+	ids         map[string]uint64      // Task.Id => UserId
+	cancelIds   map[string]bool        // Ids cancelled while queued
 	nextId      uint64
-	errCmds     uint64
+	draining    bool
+	closed      bool            // gave up on scamper
+	drainCtx    context.Context // bounds how long Drain waits
+	abandoned   int
 	mu          *sync.RWMutex
 
 	taskQ      chan measurement.Task
@@ -36,34 +171,83 @@ type Controller struct {
 	taskWg     *sync.WaitGroup
 
 	resQ      chan measurement.Task
+	eventQ    chan ConnEvent
 	resCancel context.CancelFunc
 	resWg     *sync.WaitGroup
+
+	// commands being handed to ScAttach, which must be given up on
+	// before it closes its command queue
+	sendCtx    context.Context
+	sendCancel context.CancelFunc
+	sendWg     *sync.WaitGroup
 }
 
 func NewController(log zerolog.Logger, cfg ControllerConfig) (*Controller, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
 	taskCtx, taskCancel := context.WithCancel(context.Background())
 	resCtx, resCancel := context.WithCancel(context.Background())
 
-	attach, err := NewScAttach(log, cfg.ScamperURL)
+	var scamper *ScamperProcess
+	if cfg.Scamper != nil {
+		var err error
+		scamper, err = NewScamperProcess(log, *cfg.Scamper)
+		if err != nil {
+			taskCancel()
+			resCancel()
+			return nil, err
+		}
+		cfg.ScamperURL = scamper.URL()
+		cfg.Attach.URL = scamper.URL()
+	}
+
+	attach, err := NewScAttach(log, cfg.Attach)
 	if err != nil {
+		taskCancel()
+		resCancel()
+		if scamper != nil {
+			scamper.Close()
+		}
 		return nil, err
 	}
 
+	sendCtx, sendCancel := context.WithCancel(context.Background())
+
 	c := &Controller{
 		log:         initLogger(log, "controller"),
 		cfg:         cfg,
+		scamper:     scamper,
 		attach:      attach,
 		outstanding: map[uint64]measurement.Task{},
+		handles:     map[uint64]*TaskHandle{},
+		cmdIds:      map[string]uint64{},
+		scIds:       map[uint64]uint64{},
+		cancelled:   map[uint64]uint64{},
+		deadlines:   map[uint64]time.Time{},
+		retryAt:     map[uint64]time.Time{},
+		inFlight:    map[string]int{},
+		waiting:     map[string][]uint64{},
+		held:        map[uint64]bool{},
+		ids:         map[string]uint64{},
+		cancelIds:   map[string]bool{},
 		nextId:      1,
 		mu:          &sync.RWMutex{},
 
-		taskQ:      make(chan measurement.Task, SEND_Q_LEN),
+		taskQ:      make(chan measurement.Task, cfg.TaskQueueLen),
 		taskCancel: taskCancel,
 		taskWg:     &sync.WaitGroup{},
 
-		resQ:      make(chan measurement.Task, RECV_Q_LEN),
+		resQ:      make(chan measurement.Task, cfg.ResultQueueLen),
+		eventQ:    make(chan ConnEvent, cfg.ResultQueueLen),
 		resCancel: resCancel,
 		resWg:     &sync.WaitGroup{},
+
+		sendCtx:    sendCtx,
+		sendCancel: sendCancel,
+		sendWg:     &sync.WaitGroup{},
 	}
 
 	// start up our task execution proxy
@@ -89,7 +273,140 @@ func (c *Controller) ResultQueue() chan measurement.Task {
 	return c.resQ
 }
 
-func (c *Controller) Drain() {
+// Changes in the state of the connection to scamper. Servicing this
+// queue is optional: events are dropped if it fills up.
+func (c *Controller) EventQueue() chan ConnEvent {
+	return c.eventQ
+}
+
+// Submits a single task to scamper and returns a handle that can be
+// used to wait for, retrieve, or cancel it. The task result is not
+// sent to the ResultQueue.
+//
+// Submit may block until scamper is ready to accept the task. It is
+// safe to call from multiple goroutines, but tasks submitted after
+// Drain has been called will fail with ErrControllerDraining.
+func (c *Controller) Submit(task measurement.Task) *TaskHandle {
+	h := newTaskHandle(c, task)
+	c.submit(context.Background(), task, h)
+	return h
+}
+
+// Like Submit, but the task is cancelled if ctx is done before the
+// task finishes.
+func (c *Controller) SubmitContext(ctx context.Context,
+	task measurement.Task) *TaskHandle {
+	h := newTaskHandle(c, task)
+	c.submit(ctx, task, h)
+	go func() {
+		select {
+		case <-h.Done():
+		case <-ctx.Done():
+			h.Cancel()
+		}
+	}()
+	return h
+}
+
+// Cancels an outstanding task. Scamper is asked to halt the task, and
+// the task is returned (on the ResultQueue, or via its TaskHandle)
+// with its Error set.
+//
+// The task is identified by its UserId, if set, and otherwise by its
+// Id. Tasks are copied when they are put on the TaskQueue, so the
+// UserId assigned to them is only seen once they come back on the
+// ResultQueue; give them an Id to be able to cancel them. A task that
+// is cancelled by Id while it is still on the TaskQueue is returned
+// as cancelled as soon as it is taken off the queue.
+//
+// ErrUnknownTask is returned if there is no outstanding task with the
+// given UserId.
+func (c *Controller) Cancel(task measurement.Task) error {
+	userId := task.UserId
+	if userId == 0 && task.Id != "" {
+		c.mu.Lock()
+		userId = c.ids[task.Id]
+		if userId == 0 {
+			// not seen yet, so hopefully still queued
+			c.cancelIds[task.Id] = true
+		}
+		c.mu.Unlock()
+		if userId == 0 {
+			return nil
+		}
+	}
+	if !c.cancelTask(userId) {
+		return fmt.Errorf("%w: %d", ErrUnknownTask, userId)
+	}
+	return nil
+}
+
+// Submits a single task to scamper and blocks until its result is
+// received, or until ctx is done. The task result is not sent to the
+// ResultQueue.
+//
+// Do is safe to call from multiple goroutines.
+func (c *Controller) Do(ctx context.Context,
+	task measurement.Task) (measurement.Result, error) {
+	h := newTaskHandle(c, task)
+	c.submit(ctx, task, h)
+	select {
+	case <-h.Done():
+		return h.Result(), h.Err()
+
+	case <-ctx.Done():
+		h.Cancel()
+		return nil, ctx.Err()
+	}
+}
+
+// Returns a handle for a task that was rejected because we are
+// draining.
+func (c *Controller) rejectTask(task measurement.Task) *TaskHandle {
+	h := newTaskHandle(c, task)
+	c.failTask(task, h, measurement.TASK_REJECTED, ErrControllerDraining)
+	return h
+}
+
+func (c *Controller) submit(ctx context.Context, task measurement.Task,
+	h *TaskHandle) {
+	c.mu.RLock()
+	draining := c.draining
+	c.mu.RUnlock()
+	if draining {
+		c.failTask(task, h, measurement.TASK_REJECTED, ErrControllerDraining)
+		return
+	}
+	if err := c.sendTask(ctx, task, h); err != nil {
+		c.failTask(task, h, sendErrStatus(err), err)
+	}
+}
+
+// Stops accepting new tasks, and waits until all outstanding tasks
+// have finished, or until ctx is done. Any tasks still outstanding
+// once ctx is done are abandoned, in which case ctx.Err() is
+// returned. The ResultQueue is closed once Drain completes, and it
+// must continue to be serviced while Drain is waiting.
+func (c *Controller) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	c.draining = true
+	c.drainCtx = ctx
+	c.mu.Unlock()
+
+	// if ctx is done first, give up on sending the tasks that are
+	// still queued (or waiting for space in ScAttach's queue)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.sendCancel()
+			c.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
 	// the caller should have stopped queueing tasks, so we
 	// first wait for our task worker to drain
 	c.taskCancel()
@@ -98,6 +415,18 @@ func (c *Controller) Drain() {
 	// now signal to the result worker that it should shut down
 	// once all outstanding results are back
 	c.resCancel()
+	c.resWg.Wait()
+
+	if c.abandoned > 0 {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (c *Controller) drainContext() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.drainCtx
 }
 
 func (c *Controller) Close() {
@@ -106,25 +435,480 @@ func (c *Controller) Close() {
 	}
 	// wait for result drain to complete (it should be)
 	c.resWg.Wait()
+	// give up on any commands still waiting to be sent, since
+	// ScAttach closes its command queue
+	c.mu.Lock()
+	c.sendCancel()
+	c.mu.Unlock()
+	c.sendWg.Wait()
 	// close our scamper handler
 	c.attach.Close()
+	// and scamper itself, if we started it
+	if c.scamper != nil {
+		c.scamper.Close()
+	}
 	c.log.Debug().Msgf("Shutdown complete")
 }
 
-func (c *Controller) sendTask(task measurement.Task) {
+// Assigns the task a UserId and sends it to scamper. If h is non-nil,
+// the finished task will be handed to it rather than to the result
+// queue.
+func (c *Controller) sendTask(ctx context.Context, task measurement.Task,
+	h *TaskHandle) error {
+	c.mu.RLock()
+	closed := c.closed
+	c.mu.RUnlock()
+	if closed {
+		return ErrConnectionLost
+	}
+	if err := task.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTask, err)
+	}
+	if c.cancelledWhileQueued(task.Id) {
+		return ErrTaskCancelled
+	}
+	task, taskCmd, held := c.registerTask(task, h, time.Time{})
+	if held {
+		// sent once its target is free
+		return nil
+	}
+	return c.pushTask(ctx, task.UserId, taskCmd)
+}
+
+// Returns true (once) if the task with the given Id was cancelled
+// before it was taken off the TaskQueue
+func (c *Controller) cancelledWhileQueued(id string) bool {
+	if id == "" {
+		return false
+	}
 	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.cancelIds[id] {
+		return false
+	}
+	delete(c.cancelIds, id)
+	return true
+}
+
+// Returns the status for a task that could not be sent
+func sendErrStatus(err error) measurement.TaskStatus {
+	if err == ErrConnectionLost || errors.Is(err, ErrInvalidTask) {
+		return measurement.TASK_REJECTED
+	}
+	if err == ErrTaskAbandoned {
+		return measurement.TASK_ABANDONED
+	}
+	return measurement.TASK_CANCELLED
+}
+
+// Returns a task that was never sent to scamper to the user, bypassing
+// the retry policy.
+func (c *Controller) failTask(task measurement.Task, h *TaskHandle,
+	status measurement.TaskStatus, err error) {
+	task.Status = status
+	task.Error = err.Error()
+	if h != nil {
+		h.finish(task, err)
+		return
+	}
+	c.resQ <- task
+}
+
+// Assigns the task a fresh UserId and starts tracking it. If retryAt is
+// set, the task is held until then (see reapTasks) rather than being
+// sent by the caller. If the task's target already has MaxPerTarget
+// tasks outstanding, the task is held back until one of them finishes
+// (see forgetTask), and held is set. A held-back retry still waits for
+// retryAt once its target is free.
+func (c *Controller) registerTask(task measurement.Task, h *TaskHandle,
+	retryAt time.Time) (measurement.Task, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	// TODO: more complex IDs?
 	task.UserId = c.nextId
 	c.nextId++
+	task.Status = measurement.TASK_PENDING
 	c.outstanding[task.UserId] = task
-	c.mu.Unlock()
+	if task.Id != "" {
+		c.ids[task.Id] = task.UserId
+	}
+	if h != nil {
+		h.sent(task)
+		c.handles[task.UserId] = h
+	}
 	taskCmd := task.AsCommand()
+	if !retryAt.IsZero() {
+		c.retryAt[task.UserId] = retryAt
+	}
+	if c.cfg.MaxPerTarget > 0 &&
+		c.inFlight[task.Target] >= c.cfg.MaxPerTarget {
+		c.held[task.UserId] = true
+		c.waiting[task.Target] = append(c.waiting[task.Target],
+			task.UserId)
+		c.log.Debug().
+			Uint64("userid", task.UserId).
+			Str("target", task.Target).
+			Int("waiting", len(c.waiting[task.Target])).
+			Msgf("Target busy, holding task back")
+		return task, taskCmd, true
+	}
+	c.inFlight[task.Target]++
+	c.cmdIds[taskCmd] = task.UserId
+	return task, taskCmd, false
+}
+
+// Hands the command for a registered task to scamper. If the command
+// can't be sent before ctx is done (or we give up on sending, see
+// Close), the task is forgotten and an error is returned (unless it
+// has already finished).
+func (c *Controller) pushTask(ctx context.Context, userId uint64,
+	taskCmd string) error {
+	if !c.trackSend() {
+		c.unsendTask(userId, taskCmd)
+		if _, _, exists := c.forgetTask(userId); !exists {
+			// already finished (e.g., cancelled)
+			return nil
+		}
+		return ErrTaskAbandoned
+	}
+	defer c.sendWg.Done()
 	c.log.Debug().
-		Interface("task", task).
+		Uint64("userid", userId).
 		Str("command", taskCmd).
 		Msgf("Sending command to scamper")
 	// this might block
-	c.attach.CommandQueue() <- taskCmd
+	var err error
+	select {
+	case c.attach.CommandQueue() <- taskCmd:
+		c.markSent(userId)
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-c.sendCtx.Done():
+	}
+	if c.sendCtx.Err() != nil {
+		// we gave up on scamper, rather than the caller giving
+		// up on the task
+		err = ErrTaskAbandoned
+	}
+	c.unsendTask(userId, taskCmd)
+	if _, _, exists := c.forgetTask(userId); !exists {
+		// finished (e.g., cancelled) while we were waiting
+		return nil
+	}
+	return err
+}
+
+// Like pushTask, but sends the command from a separate goroutine so
+// that the caller (usually the response handler) doesn't block. If we
+// give up on sending, the task is left outstanding so that it is
+// abandoned along with the others.
+func (c *Controller) pushLater(userId uint64, taskCmd string) {
+	if !c.trackSend() {
+		c.unsendTask(userId, taskCmd)
+		return
+	}
+	go func() {
+		defer c.sendWg.Done()
+		c.log.Debug().
+			Uint64("userid", userId).
+			Str("command", taskCmd).
+			Msgf("Sending command to scamper")
+		select {
+		case c.attach.CommandQueue() <- taskCmd:
+			c.markSent(userId)
+		case <-c.sendCtx.Done():
+			c.unsendTask(userId, taskCmd)
+		}
+	}()
+}
+
+// Registers a command that is about to be handed to ScAttach, unless
+// we have given up on sending. The caller must call sendWg.Done once
+// the command has been handed off.
+func (c *Controller) trackSend() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sendCtx.Err() != nil {
+		return false
+	}
+	c.sendWg.Add(1)
+	return true
+}
+
+// Stops expecting a response to a command that was never sent
+func (c *Controller) unsendTask(userId uint64, taskCmd string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cmdIds[taskCmd] == userId {
+		delete(c.cmdIds, taskCmd)
+	}
+}
+
+// Marks a task as sent to scamper, and starts its timeout (if any).
+func (c *Controller) markSent(userId uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	task, exists := c.outstanding[userId]
+	if !exists {
+		return
+	}
+	// scamper may already have accepted the task
+	if task.Status < measurement.TASK_SENT {
+		task.Status = measurement.TASK_SENT
+		c.outstanding[userId] = task
+	}
+	deadline := task.Deadline
+	timeout := task.Timeout
+	if timeout == 0 && deadline.IsZero() {
+		timeout = c.cfg.DefaultTimeout
+	}
+	if timeout > 0 {
+		if d := time.Now().Add(timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if !deadline.IsZero() {
+		c.deadlines[userId] = deadline
+	}
+}
+
+// Stops tracking a task. If scamper later returns a result for it, the
+// result will be discarded.
+func (c *Controller) forgetTask(userId uint64) (measurement.Task, *TaskHandle, bool) {
+	c.mu.Lock()
+	task, exists := c.outstanding[userId]
+	h := c.handles[userId]
+	delete(c.outstanding, userId)
+	delete(c.handles, userId)
+	delete(c.scIds, userId)
+	delete(c.deadlines, userId)
+	if exists && c.ids[task.Id] == userId {
+		delete(c.ids, task.Id)
+	}
+	if _, waiting := c.retryAt[userId]; waiting {
+		// never sent, so scamper won't respond to it
+		delete(c.retryAt, userId)
+		delete(c.cmdIds, task.AsCommand())
+	}
+	var nextId uint64
+	var nextCmd string
+	if c.held[userId] {
+		c.unholdTask(userId, task.Target)
+	} else if exists {
+		nextId, nextCmd = c.releaseTarget(task.Target)
+	}
+	c.mu.Unlock()
+	if nextId != 0 {
+		// this might block, and we need to keep servicing
+		// responses from scamper
+		c.pushLater(nextId, nextCmd)
+	}
+	return task, h, exists
+}
+
+// Removes a held-back task from its target's waiting list. Must be
+// called with mu held.
+func (c *Controller) unholdTask(userId uint64, target string) {
+	delete(c.held, userId)
+	ids := c.waiting[target]
+	for i, id := range ids {
+		if id == userId {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(c.waiting, target)
+	} else {
+		c.waiting[target] = ids
+	}
+}
+
+// Frees a slot for the target, and returns the next held-back task
+// towards it (if any), which now occupies the slot and should be sent
+// by the caller. If that task is a retry that isn't yet due, it is left
+// for reapTasks to send instead. Must be called with mu held.
+func (c *Controller) releaseTarget(target string) (uint64, string) {
+	c.inFlight[target]--
+	if c.inFlight[target] <= 0 {
+		delete(c.inFlight, target)
+	}
+	ids := c.waiting[target]
+	if len(ids) == 0 {
+		return 0, ""
+	}
+	userId := ids[0]
+	c.unholdTask(userId, target)
+	c.inFlight[target]++
+	taskCmd := c.outstanding[userId].AsCommand()
+	c.cmdIds[taskCmd] = userId
+	c.log.Debug().
+		Uint64("userid", userId).
+		Str("target", target).
+		Msgf("Target free, releasing held task")
+	if retryAt, waiting := c.retryAt[userId]; waiting &&
+		time.Now().Before(retryAt) {
+		return 0, ""
+	}
+	delete(c.retryAt, userId)
+	return userId, taskCmd
+}
+
+func (c *Controller) cancelTask(userId uint64) bool {
+	return c.stopTask(userId, measurement.TASK_CANCELLED, ErrTaskCancelled)
+}
+
+// Stops an outstanding task, asking scamper to halt it if it has
+// already been accepted, and finishes it with the given status.
+// Returns false if there was no such task outstanding.
+func (c *Controller) stopTask(userId uint64, status measurement.TaskStatus,
+	err error) bool {
+	c.mu.Lock()
+	scId, accepted := c.scIds[userId]
+	_, exists := c.outstanding[userId]
+	_, waiting := c.retryAt[userId]
+	if exists && !waiting && !c.held[userId] {
+		// remember this so that we can halt the task once
+		// scamper accepts it, and quietly discard the result
+		c.cancelled[userId] = scId
+	}
+	c.mu.Unlock()
+	if !exists {
+		return false
+	}
+	task, h, exists := c.forgetTask(userId)
+	if !exists {
+		// lost a race with the result
+		return true
+	}
+	if accepted {
+		c.haltTask(userId, scId)
+	}
+	c.log.Debug().
+		Uint64("userid", userId).
+		Bool("accepted", accepted).
+		Str("status", status.String()).
+		Msgf("Stopped task")
+	task.Status = status
+	task.Error = err.Error()
+	c.finishTask(task, h, err)
+	return true
+}
+
+// Stops any outstanding tasks that have passed their deadline, and
+// sends any retries that are due.
+func (c *Controller) reapTasks() {
+	now := time.Now()
+	c.mu.Lock()
+	expired := []uint64{}
+	for userId, deadline := range c.deadlines {
+		if now.After(deadline) {
+			expired = append(expired, userId)
+		}
+	}
+	for userId := range c.held {
+		// held-back tasks have no deadline until sent, but may
+		// still pass an absolute one
+		if deadline := c.outstanding[userId].Deadline; !deadline.IsZero() &&
+			now.After(deadline) {
+			expired = append(expired, userId)
+		}
+	}
+	retries := map[uint64]string{}
+	for userId, retryAt := range c.retryAt {
+		if !c.held[userId] && !now.Before(retryAt) {
+			retries[userId] = c.outstanding[userId].AsCommand()
+			delete(c.retryAt, userId)
+		}
+	}
+	c.mu.Unlock()
+	for userId, taskCmd := range retries {
+		// this might block, and we need to keep servicing
+		// responses from scamper
+		c.pushLater(userId, taskCmd)
+	}
+	for _, userId := range expired {
+		c.log.Debug().
+			Uint64("userid", userId).
+			Msgf("Task timed out")
+		c.stopTask(userId, measurement.TASK_TIMED_OUT, ErrTaskTimedOut)
+	}
+}
+
+func (c *Controller) haltTask(userId uint64, scId uint64) {
+	c.log.Debug().
+		Uint64("userid", userId).
+		Uint64("task-id", scId).
+		Msgf("Halting scamper task")
+	c.attach.Halt(scId)
+}
+
+func (c *Controller) handleAck(ack ScAck) {
+	c.mu.Lock()
+	userId, exists := c.cmdIds[ack.Cmd]
+	delete(c.cmdIds, ack.Cmd)
+	task, outstanding := c.outstanding[userId]
+	_, cancelled := c.cancelled[userId]
+	if outstanding {
+		c.scIds[userId] = ack.TaskId
+		task.Status = measurement.TASK_ACCEPTED
+		c.outstanding[userId] = task
+	} else if cancelled {
+		c.cancelled[userId] = ack.TaskId
+	}
+	c.mu.Unlock()
+
+	if !exists {
+		c.log.Warn().
+			Str("command", ack.Cmd).
+			Msgf("Couldn't find task for scamper ack")
+		return
+	}
+	if cancelled {
+		// cancelled before scamper accepted it
+		c.haltTask(userId, ack.TaskId)
+	}
+}
+
+// Hands a finished task to its handle, or to the result queue if it
+// has none.
+func (c *Controller) finishTask(task measurement.Task, h *TaskHandle,
+	err error) {
+	if c.retryTask(task, h) {
+		return
+	}
+	if h != nil {
+		h.finish(task, err)
+		return
+	}
+	c.resQ <- task
+}
+
+// Schedules another attempt at a failed task, if the retry policy
+// allows it.
+func (c *Controller) retryTask(task measurement.Task, h *TaskHandle) bool {
+	now := time.Now()
+	if !c.cfg.Retry.shouldRetry(task, now) {
+		return false
+	}
+	task.Attempts = append(task.Attempts, measurement.TaskAttempt{
+		UserId: task.UserId,
+		Status: task.Status,
+		Error:  task.Error,
+	})
+	task.Result = nil
+	task.Error = ""
+	delay := c.cfg.Retry.backoff(len(task.Attempts))
+	prevId := task.UserId
+	task, _, _ = c.registerTask(task, h, now.Add(delay))
+	c.log.Debug().
+		Uint64("userid", task.UserId).
+		Uint64("prev-userid", prevId).
+		Int("attempt", len(task.Attempts)+1).
+		Dur("backoff", delay).
+		Msgf("Retrying task")
+	return true
 }
 
 func (c *Controller) taskHandler(ctx context.Context) {
@@ -139,7 +923,14 @@ hamster:
 	for {
 		select {
 		case task := <-c.taskQ:
-			c.sendTask(task)
+			// this might block until Drain gives up
+			if err := c.sendTask(c.sendCtx, task, nil); err != nil {
+				status := sendErrStatus(err)
+				if status == measurement.TASK_ABANDONED {
+					c.abandoned++
+				}
+				c.failTask(task, nil, status, err)
+			}
 
 		case <-ctx.Done():
 			// canceled, need to drain taskQ and then exit
@@ -154,134 +945,313 @@ hamster:
 		Int("queue-length", len(c.taskQ)).
 		Msgf("Draining task queue")
 	for len(c.taskQ) > 0 {
-		c.sendTask(<-c.taskQ)
+		task := <-c.taskQ
+		if err := c.sendTask(c.sendCtx, task, nil); err != nil {
+			status := measurement.TASK_ABANDONED
+			if errors.Is(err, ErrInvalidTask) {
+				status = measurement.TASK_REJECTED
+			} else if err == ErrTaskCancelled {
+				status = measurement.TASK_CANCELLED
+			} else {
+				c.abandoned++
+			}
+			c.failTask(task, nil, status, err)
+		}
 	}
 	c.log.Debug().
 		Msgf("Task queue drained")
 }
 
 func (c *Controller) handleResult(resStr string) {
-	scRes, err := measurement.NewScResultFromJson(resStr)
-	if err != nil {
-		// TODO: handle these in-band
+	scRes, parseErr := measurement.NewResultFromJson(resStr)
+	if scRes == nil {
 		c.log.Error().
-			Err(err).
+			Err(parseErr).
 			Msgf("Failed to parse result from scamper")
 		return
 	}
 
-	// discard the initial cycle-start we get after we attach
-	if scRes.Type == "cycle-start" {
+	// discard the cycle objects (e.g., the initial cycle-start we
+	// get after we attach)
+	switch scRes.(type) {
+	case *measurement.CycleResult, *measurement.ListResult:
 		c.log.Debug().
 			Str("result", resStr).
-			Msgf("Discarding cycle-start")
+			Msgf("Discarding %s", scRes.ResultType())
 		return
 	}
 
+	userId := scRes.ResultUserId()
+	task, h, exists := c.forgetTask(userId)
+
 	c.mu.Lock()
-	task, exists := c.outstanding[scRes.UserID]
-	delete(c.outstanding, scRes.UserID)
+	_, cancelled := c.cancelled[userId]
+	delete(c.cancelled, userId)
 	c.mu.Unlock()
+	if cancelled {
+		c.log.Debug().
+			Uint64("userid", userId).
+			Msgf("Discarding result for cancelled task")
+		return
+	}
 
 	if !exists {
 		c.log.Error().
 			Interface("sc-result", scRes).
-			Uint64("userid", scRes.UserID).
+			Uint64("userid", userId).
 			Msgf("Couldn't find task for scamper result")
 		return
 	}
 
 	task.Result = scRes
-	c.resQ <- task
+	task.Status = measurement.TASK_COMPLETED
+	if parseErr != nil {
+		// we still hand back the raw result
+		c.log.Error().
+			Err(parseErr).
+			Uint64("userid", userId).
+			Msgf("Failed to parse result from scamper")
+		task.Error = parseErr.Error()
+	}
+	c.finishTask(task, h, parseErr)
 }
 
-func (c *Controller) handleError(errStr string) {
-	c.log.Error().
-		Str("error", errStr).
-		Msgf("Received error from scamper")
+func (c *Controller) handleError(scErr ScError) {
+	c.mu.Lock()
+	userId, exists := c.cmdIds[scErr.Cmd]
+	delete(c.cmdIds, scErr.Cmd)
+	delete(c.cancelled, userId)
+	c.mu.Unlock()
 
-	// if we get a 'command not accepted' error, we don't know
-	// which command exactly failed, but we can increment a
-	// counter so that when we shut down we don't bother to wait
-	// for this result
-	atomic.AddUint64(&c.errCmds, 1)
+	if !exists {
+		c.log.Error().
+			Str("command", scErr.Cmd).
+			Str("error", scErr.Err).
+			Msgf("Received error from scamper")
+		return
+	}
 
-	// TODO: handle these in-band
+	task, h, exists := c.forgetTask(userId)
+	if !exists {
+		// already cancelled
+		return
+	}
+	c.log.Warn().
+		Uint64("userid", userId).
+		Str("command", scErr.Cmd).
+		Str("error", scErr.Err).
+		Msgf("Task rejected by scamper")
+	task.Status = measurement.TASK_REJECTED
+	task.Error = scErr.Err
+	c.finishTask(task, h, scErr)
 }
 
 func (c *Controller) Outstanding() int {
 	c.mu.RLock()
-	rem := len(c.outstanding)
-	c.mu.RUnlock()
-	errs := atomic.LoadUint64(&c.errCmds)
-	if rem >= int(errs) {
-		return rem - int(errs)
+	defer c.mu.RUnlock()
+	return len(c.outstanding)
+}
+
+// Number of outstanding tasks that are being held back because their
+// target is busy (see ControllerConfig.MaxPerTarget)
+func (c *Controller) Waiting() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.held)
+}
+
+func (c *Controller) handleConnEvent(ev ConnEvent) {
+	c.log.Info().
+		Str("state", ev.State.String()).
+		AnErr("reason", ev.Err).
+		Int("lost", len(ev.Lost)).
+		Msgf("Scamper connection state changed")
+
+	switch ev.State {
+	case CONN_DISCONNECTED:
+		c.handleLostTasks(ev.Lost)
+
+	case CONN_CLOSED:
+		// we won't hear from scamper again, so give up on
+		// everything still outstanding
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		c.abandonAll(ErrConnectionLost)
+	}
+
+	select {
+	case c.eventQ <- ev:
+	default:
+		c.log.Warn().Msgf("Event queue full, dropping event")
 	}
-	// this is odd
-	c.log.Warn().
-		Int("outstanding", rem).
-		Uint64("errors", errs).
-		Msgf("More errors than outstanding tasks")
-	return rem
+}
+
+// Resends (or abandons, depending on the replay policy) the tasks that
+// were lost when the connection to scamper dropped. These are the
+// tasks that scamper had accepted, along with any that were sent but
+// not yet accepted.
+func (c *Controller) handleLostTasks(lostCmds []string) {
+	c.mu.Lock()
+	lost := map[uint64]bool{} // UserId => accepted
+	for _, cmd := range lostCmds {
+		if userId, exists := c.cmdIds[cmd]; exists {
+			delete(c.cmdIds, cmd)
+			lost[userId] = false
+		}
+	}
+	for userId := range c.scIds {
+		lost[userId] = true
+	}
+	for userId, scId := range c.cancelled {
+		// scamper won't respond to these now
+		if _, sent := lost[userId]; sent || scId != 0 {
+			delete(c.cancelled, userId)
+		}
+	}
+	c.mu.Unlock()
+
+	now := time.Now()
+	for userId, accepted := range lost {
+		task, h, exists := c.forgetTask(userId)
+		if !exists {
+			continue
+		}
+		replay := c.cfg.Replay == REPLAY_ALL ||
+			(c.cfg.Replay == REPLAY_UNACCEPTED && !accepted)
+		if !replay {
+			task.Status = measurement.TASK_ABANDONED
+			task.Error = ErrConnectionLost.Error()
+			c.finishTask(task, h, ErrConnectionLost)
+			continue
+		}
+		// this will be sent once we have reconnected
+		prevId := task.UserId
+		task, _, _ = c.registerTask(task, h, now)
+		c.log.Debug().
+			Uint64("userid", task.UserId).
+			Uint64("prev-userid", prevId).
+			Bool("accepted", accepted).
+			Msgf("Replaying lost task")
+	}
+}
+
+// Returns all outstanding tasks to the user, and returns how many
+// there were.
+func (c *Controller) abandonAll(err error) int {
+	c.mu.Lock()
+	abandoned := make([]uint64, 0, len(c.outstanding))
+	// forget held-back tasks first so that they aren't released
+	// (and sent) as the others are forgotten
+	for userId := range c.held {
+		abandoned = append(abandoned, userId)
+	}
+	for userId := range c.outstanding {
+		if !c.held[userId] {
+			abandoned = append(abandoned, userId)
+		}
+	}
+	c.mu.Unlock()
+	for _, userId := range abandoned {
+		if task, h, exists := c.forgetTask(userId); exists {
+			task.Status = measurement.TASK_ABANDONED
+			task.Error = err.Error()
+			c.finishTask(task, h, err)
+		}
+	}
+	return len(abandoned)
 }
 
 func (c *Controller) responseHandler(ctx context.Context) {
 	defer func() {
 		close(c.resQ)
+		close(c.eventQ)
 		c.resWg.Done()
 	}()
 
-	// service both the result and error queues from ScAttach
+	// service the queues from ScAttach (which are closed if we
+	// give up on scamper)
 	resultQ := c.attach.ResultQueue()
 	errQ := c.attach.ErrorQueue()
-hamster:
-	for {
+	ackQ := c.attach.AckQueue()
+	eventQ := c.attach.EventQueue()
+	// and periodically check for tasks that have timed out
+	reapTicker := time.NewTicker(TIMEOUT_CHECK_INTERVAL)
+	defer reapTicker.Stop()
+	// handles everything that ScAttach queued before an event
+	flush := func() {
+		for len(ackQ) > 0 {
+			c.handleAck(<-ackQ)
+		}
+		for len(errQ) > 0 {
+			c.handleError(<-errQ)
+		}
+		for len(resultQ) > 0 {
+			c.handleResult(<-resultQ)
+		}
+	}
+	service := func(ctx context.Context) bool {
 		select {
-		case resStr := <-resultQ:
+		case resStr, ok := <-resultQ:
+			if !ok {
+				resultQ = nil
+				break
+			}
 			c.handleResult(resStr)
 
-		case errStr := <-errQ:
-			c.handleError(errStr)
+		case ack, ok := <-ackQ:
+			if !ok {
+				ackQ = nil
+				break
+			}
+			c.handleAck(ack)
+
+		case scErr, ok := <-errQ:
+			if !ok {
+				errQ = nil
+				break
+			}
+			c.handleError(scErr)
+
+		case ev, ok := <-eventQ:
+			if !ok {
+				eventQ = nil
+				break
+			}
+			flush()
+			c.handleConnEvent(ev)
+
+		case <-reapTicker.C:
+			c.reapTasks()
 
 		case <-ctx.Done():
-			// canceled, need to drain both queues
-			break hamster
+			return false
 		}
+		return true
+	}
+
+	for service(ctx) {
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_LINGER)
-	defer cancel()
+	// canceled, wait for the remaining results
+	ctx = c.drainContext()
 	rem := c.Outstanding()
 	c.log.Info().
 		Int("outstanding", rem).
-		Dur("linger", SHUTDOWN_LINGER).
 		Msgf("Waiting for remaining tasks to complete")
-drain:
-	for {
-		select {
-		case resStr := <-resultQ:
-			c.handleResult(resStr)
-			if c.Outstanding() == 0 {
-				// done
-				break drain
-			}
-
-		case errStr := <-errQ:
-			c.handleError(errStr)
-
-		case <-ctx.Done():
+	for c.Outstanding() > 0 {
+		if !service(ctx) {
 			c.log.Error().
 				Msgf("Giving up waiting for results from scamper")
-			break drain
+			break
 		}
 	}
 
 	// dump any tasks still outstanding back to the user
 	// these could be errors, or things that we gave up waiting for
+	abandoned := c.abandonAll(ErrTaskAbandoned)
+	c.abandoned += abandoned
 	c.log.Debug().
-		Int("abandoned", len(c.outstanding)).
+		Int("abandoned", abandoned).
 		Msgf("Received all results from scamper")
-	for _, task := range c.outstanding {
-		c.resQ <- task
-	}
 }
@@ -0,0 +1,628 @@
+package scurry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alistairking/scurry/measurement"
+	"github.com/rs/zerolog"
+)
+
+// Just enough of a scamper control socket to drive a Controller.
+// Pings are accepted and answered straight away (unless rejects,
+// silent or ignore say otherwise), and anything else is rejected. A stuck
+// fakeScamper accepts the attach but never asks for a command.
+type fakeScamper struct {
+	stuck bool
+	// Pings towards these targets are rejected this many times
+	// before being accepted
+	rejects map[string]int
+	// Pings towards these targets are accepted, but never answered
+	silent map[string]bool
+	// Pings towards these targets are neither accepted nor
+	// rejected (and no more commands are asked for)
+	ignore map[string]bool
+
+	mu     sync.Mutex
+	nextId int
+	conns  []net.Conn
+	pings  map[string][]time.Time // target => when each ping arrived
+	halted []string               // scamper task IDs
+}
+
+// Serves a fake scamper on a unix socket, and returns its path
+func (f *fakeScamper) listen(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scamper.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ln.Close()
+		f.dropAll()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return path
+}
+
+// Speaks the scamper control protocol on conn until it is closed
+func (f *fakeScamper) serve(conn net.Conn) {
+	f.mu.Lock()
+	f.conns = append(f.conns, conn)
+	f.mu.Unlock()
+	defer conn.Close()
+
+	w := bufio.NewWriter(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "attach":
+			fmt.Fprintf(w, "OK\n")
+			if !f.stuck {
+				fmt.Fprintf(w, "MORE\n")
+			}
+		case "halt":
+			f.mu.Lock()
+			f.halted = append(f.halted, fields[len(fields)-1])
+			f.mu.Unlock()
+			fmt.Fprintf(w, "OK\n")
+		case "ping":
+			target := fields[len(fields)-1]
+			f.mu.Lock()
+			if f.pings == nil {
+				f.pings = map[string][]time.Time{}
+			}
+			f.pings[target] = append(f.pings[target], time.Now())
+			reject := f.rejects[target] > 0
+			if reject {
+				f.rejects[target]--
+			}
+			f.nextId++
+			taskId := f.nextId
+			silent, ignore := f.silent[target], f.ignore[target]
+			f.mu.Unlock()
+			if ignore {
+				break
+			}
+			if reject {
+				fmt.Fprintf(w, "ERR target unreachable\n")
+				fmt.Fprintf(w, "MORE\n")
+				break
+			}
+			if silent {
+				fmt.Fprintf(w, "OK id-%d\n", taskId)
+				fmt.Fprintf(w, "MORE\n")
+				break
+			}
+			var userId uint64
+			if len(fields) > 2 && fields[1] == "-U" {
+				userId, _ = strconv.ParseUint(fields[2], 10, 64)
+			}
+			res := fmt.Sprintf(`{"type":"ping", "version":"0.4", `+
+				`"method":"icmp-echo", "dst":"%s", "userid":%d, `+
+				`"ping_sent":1, "responses":[], `+
+				`"statistics":{"replies":0, "loss":1}}`,
+				target, userId)
+			fmt.Fprintf(w, "OK id-%d\n", taskId)
+			fmt.Fprintf(w, "DATA %d\n%s\n", len(res)+1, res)
+			fmt.Fprintf(w, "MORE\n")
+		default:
+			fmt.Fprintf(w, "ERR command not understood\n")
+			fmt.Fprintf(w, "MORE\n")
+		}
+		if w.Flush() != nil {
+			return
+		}
+	}
+}
+
+// Returns when each ping towards the target arrived
+func (f *fakeScamper) pingTimes(target string) []time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Time{}, f.pings[target]...)
+}
+
+// Answers every ping from now on
+func (f *fakeScamper) answerAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.silent = nil
+	f.ignore = nil
+}
+
+func (f *fakeScamper) haltedTasks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.halted...)
+}
+
+// Drops every connection to the fake scamper
+func (f *fakeScamper) dropAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+func newTestController(t *testing.T, cfg ControllerConfig) *Controller {
+	t.Helper()
+	ctrl, err := NewController(zerolog.Nop(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctrl
+}
+
+// Collects everything from the controller's result queue until it is
+// closed
+func collectResults(ctrl *Controller) chan []measurement.Task {
+	return collectQueue(ctrl.ResultQueue())
+}
+
+// Collects everything from a result queue until it is closed
+func collectQueue(q chan measurement.Task) chan []measurement.Task {
+	done := make(chan []measurement.Task, 1)
+	go func() {
+		tasks := []measurement.Task{}
+		for task := range q {
+			tasks = append(tasks, task)
+		}
+		done <- tasks
+	}()
+	return done
+}
+
+func pingTask(target string) measurement.Task {
+	return measurement.Task{Type: measurement.TYPE_PING, Target: target}
+}
+
+func TestControllerPing(t *testing.T) {
+	path := (&fakeScamper{}).listen(t)
+	ctrl := newTestController(t, ControllerConfig{ScamperURL: path})
+	results := collectResults(ctrl)
+
+	for i := 1; i <= 3; i++ {
+		ctrl.TaskQueue() <- pingTask(fmt.Sprintf("192.0.2.%d", i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ctrl.Drain(ctx); err != nil {
+		t.Fatalf("Drain() = %v", err)
+	}
+	ctrl.Close()
+
+	tasks := <-results
+	if len(tasks) != 3 {
+		t.Fatalf("got %d results, want 3", len(tasks))
+	}
+	for _, task := range tasks {
+		ping, ok := task.AsPing()
+		if task.Status != measurement.TASK_COMPLETED || !ok ||
+			ping.Dst != task.Target || ping.UserID != task.UserId {
+			t.Errorf("bad result: %+v", task)
+		}
+	}
+}
+
+// Tasks released (or retried) from the response handler may still be
+// waiting to be sent when we give up on scamper. Closing the
+// controller must not then send them on ScAttach's closed command
+// queue.
+func TestControllerCloseWithBlockedSends(t *testing.T) {
+	path := (&fakeScamper{stuck: true}).listen(t)
+	ctrl := newTestController(t, ControllerConfig{
+		ScamperURL:     path,
+		Attach:         ScAttachConfig{CommandQueueLen: 1},
+		MaxPerTarget:   1,
+		DefaultTimeout: 50 * time.Millisecond,
+	})
+	results := collectResults(ctrl)
+
+	for i := 0; i < 5; i++ {
+		ctrl.TaskQueue() <- pingTask("192.0.2.1")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		400*time.Millisecond)
+	defer cancel()
+	if err := ctrl.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Drain() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	ctrl.Close()
+
+	tasks := <-results
+	if len(tasks) != 5 {
+		t.Fatalf("got %d results, want 5", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Status != measurement.TASK_TIMED_OUT &&
+			task.Status != measurement.TASK_ABANDONED {
+			t.Errorf("task %d: status %s", task.UserId, task.Status)
+		}
+	}
+}
+
+// Drain must give up once its context is done, even if the task
+// handler is stuck waiting for space in ScAttach's command queue
+func TestControllerDrainWithStuckScamper(t *testing.T) {
+	path := (&fakeScamper{stuck: true}).listen(t)
+	ctrl := newTestController(t, ControllerConfig{
+		ScamperURL: path,
+		Attach:     ScAttachConfig{CommandQueueLen: 2},
+	})
+	results := collectResults(ctrl)
+
+	for i := 1; i <= 10; i++ {
+		ctrl.TaskQueue() <- pingTask(fmt.Sprintf("192.0.2.%d", i))
+	}
+	// one command is waiting for a MORE, two fill the command
+	// queue, and the task handler is stuck on the fourth
+	for ctrl.Outstanding() < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		500*time.Millisecond)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() {
+		drained <- ctrl.Drain(ctx)
+	}()
+	select {
+	case err := <-drained:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Drain() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Drain() ignored its context")
+	}
+	ctrl.Close()
+
+	tasks := <-results
+	if len(tasks) != 10 {
+		t.Fatalf("got %d results, want 10", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Status != measurement.TASK_ABANDONED {
+			t.Errorf("task towards %s: status %s", task.Target, task.Status)
+		}
+	}
+}
+
+func TestControllerCancel(t *testing.T) {
+	path := (&fakeScamper{stuck: true}).listen(t)
+	ctrl := newTestController(t, ControllerConfig{
+		ScamperURL: path,
+		Attach:     ScAttachConfig{CommandQueueLen: 1},
+	})
+	results := collectResults(ctrl)
+
+	h := ctrl.Submit(pingTask("192.0.2.1"))
+	// the caller's copy of a task sent on the TaskQueue has no
+	// UserId, so these are cancelled by Id
+	for i, id := range []string{"filler", "sent", "queued"} {
+		task := pingTask(fmt.Sprintf("192.0.2.%d", i+2))
+		task.Id = id
+		ctrl.TaskQueue() <- task
+	}
+	// the submitted task is waiting for a MORE, "filler" fills the
+	// command queue, and the task handler is stuck sending "sent",
+	// which leaves "queued" on the TaskQueue
+	for ctrl.Outstanding() < 3 || len(ctrl.TaskQueue()) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, id := range []string{"sent", "queued"} {
+		if err := ctrl.Cancel(measurement.Task{Id: id}); err != nil {
+			t.Errorf("Cancel(%s) = %v", id, err)
+		}
+	}
+
+	if err := ctrl.Cancel(h.Task()); err != nil {
+		t.Errorf("Cancel() = %v", err)
+	}
+	<-h.Done()
+	if task := h.Task(); task.Status != measurement.TASK_CANCELLED ||
+		h.Err() != ErrTaskCancelled {
+		t.Errorf("cancelled task: status %s, error %v", task.Status, h.Err())
+	}
+	if err := ctrl.Cancel(h.Task()); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("second Cancel() = %v, want %v", err, ErrUnknownTask)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+	ctrl.Drain(ctx)
+	ctrl.Close()
+
+	got := map[string]measurement.TaskStatus{}
+	for _, task := range <-results {
+		if _, dup := got[task.Id]; dup {
+			t.Errorf("task %s returned twice", task.Id)
+		}
+		got[task.Id] = task.Status
+	}
+	want := map[string]measurement.TaskStatus{
+		"filler": measurement.TASK_ABANDONED,
+		"sent":   measurement.TASK_CANCELLED,
+		"queued": measurement.TASK_CANCELLED,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+// Waits for the controller to report the given connection state
+func waitEvent(t *testing.T, ctrl *Controller, state ConnState) ConnEvent {
+	t.Helper()
+	for {
+		select {
+		case ev := <-ctrl.EventQueue():
+			if ev.State == state {
+				return ev
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event", state)
+		}
+	}
+}
+
+// Waits until scamper has accepted the task towards target
+func waitAccepted(t *testing.T, ctrl *Controller, target string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ctrl.mu.RLock()
+		accepted := false
+		for _, task := range ctrl.outstanding {
+			if task.Target == target &&
+				task.Status == measurement.TASK_ACCEPTED {
+				accepted = true
+			}
+		}
+		ctrl.mu.RUnlock()
+		if accepted {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task towards %s not accepted", target)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Tasks in flight when the connection drops are replayed once we have
+// reconnected, or abandoned, according to the replay policy
+func TestControllerReplay(t *testing.T) {
+	const accepted, unaccepted = "192.0.2.1", "192.0.2.2"
+	tests := []struct {
+		policy                 ReplayPolicy
+		wantAccepted, wantSent measurement.TaskStatus
+	}{
+		{REPLAY_ALL, measurement.TASK_COMPLETED, measurement.TASK_COMPLETED},
+		{REPLAY_UNACCEPTED, measurement.TASK_ABANDONED, measurement.TASK_COMPLETED},
+		{REPLAY_NONE, measurement.TASK_ABANDONED, measurement.TASK_ABANDONED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			f := &fakeScamper{
+				silent: map[string]bool{accepted: true},
+				ignore: map[string]bool{unaccepted: true},
+			}
+			ctrl := newTestController(t, ControllerConfig{
+				ScamperURL: f.listen(t),
+				Attach: ScAttachConfig{
+					ReconnectBackoff: 10 * time.Millisecond,
+				},
+				Replay: tt.policy,
+			})
+			results := collectResults(ctrl)
+
+			ctrl.TaskQueue() <- pingTask(accepted)
+			waitAccepted(t, ctrl, accepted)
+			ctrl.TaskQueue() <- pingTask(unaccepted)
+			for len(f.pingTimes(unaccepted)) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			f.answerAll()
+			f.dropAll()
+			ev := waitEvent(t, ctrl, CONN_DISCONNECTED)
+			if len(ev.Lost) != 1 || !strings.HasSuffix(ev.Lost[0], unaccepted) {
+				t.Errorf("lost commands = %q", ev.Lost)
+			}
+			waitEvent(t, ctrl, CONN_CONNECTED)
+
+			ctx, cancel := context.WithTimeout(context.Background(),
+				5*time.Second)
+			defer cancel()
+			if err := ctrl.Drain(ctx); err != nil {
+				t.Errorf("Drain() = %v", err)
+			}
+			ctrl.Close()
+
+			tasks := <-results
+			if len(tasks) != 2 {
+				t.Fatalf("got %d results, want 2", len(tasks))
+			}
+			for _, task := range tasks {
+				want := tt.wantSent
+				if task.Target == accepted {
+					want = tt.wantAccepted
+				}
+				wantErr := ""
+				if want == measurement.TASK_ABANDONED {
+					wantErr = ErrConnectionLost.Error()
+				}
+				if task.Status != want || task.Error != wantErr {
+					t.Errorf("task towards %s: status %s (%q), want %s",
+						task.Target, task.Status, task.Error, want)
+				}
+				// replayed tasks are sent again
+				pings := 1
+				if want == measurement.TASK_COMPLETED {
+					pings = 2
+				}
+				if got := len(f.pingTimes(task.Target)); got != pings {
+					t.Errorf("%d pings towards %s, want %d", got,
+						task.Target, pings)
+				}
+			}
+		})
+	}
+}
+
+// Once we give up on scamper, outstanding tasks are abandoned and new
+// ones are rejected
+func TestControllerConnClosed(t *testing.T) {
+	tests := []struct {
+		name   string
+		attach ScAttachConfig
+	}{
+		{"reconnect disabled", ScAttachConfig{DisableReconnect: true}},
+		{"reconnect failed", ScAttachConfig{
+			ReconnectBackoff:     10 * time.Millisecond,
+			MaxReconnectAttempts: 2,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeScamper{silent: map[string]bool{"192.0.2.1": true}}
+			path := f.listen(t)
+			ctrl := newTestController(t, ControllerConfig{
+				ScamperURL: path,
+				Attach:     tt.attach,
+			})
+			results := collectResults(ctrl)
+
+			ctrl.TaskQueue() <- pingTask("192.0.2.1")
+			waitAccepted(t, ctrl, "192.0.2.1")
+			// scamper goes away for good
+			os.Remove(path)
+			f.dropAll()
+			waitEvent(t, ctrl, CONN_DISCONNECTED)
+			if ev := waitEvent(t, ctrl, CONN_CLOSED); ev.Err == nil {
+				t.Errorf("closed event has no error")
+			}
+			if ctrl.Outstanding() != 0 {
+				t.Errorf("%d tasks still outstanding", ctrl.Outstanding())
+			}
+			ctrl.TaskQueue() <- pingTask("192.0.2.2")
+
+			ctx, cancel := context.WithTimeout(context.Background(),
+				time.Second)
+			defer cancel()
+			ctrl.Drain(ctx)
+			ctrl.Close()
+
+			want := map[string]measurement.TaskStatus{
+				"192.0.2.1": measurement.TASK_ABANDONED,
+				"192.0.2.2": measurement.TASK_REJECTED,
+			}
+			tasks := <-results
+			if len(tasks) != 2 {
+				t.Fatalf("got %d results, want 2", len(tasks))
+			}
+			for _, task := range tasks {
+				if task.Status != want[task.Target] ||
+					task.Error != ErrConnectionLost.Error() {
+					t.Errorf("task towards %s: status %s (%q), want %s",
+						task.Target, task.Status, task.Error,
+						want[task.Target])
+				}
+			}
+		})
+	}
+}
+
+// Tasks that overrun their Timeout, Deadline or the DefaultTimeout are
+// halted and returned as timed out
+func TestControllerTimeout(t *testing.T) {
+	f := &fakeScamper{silent: map[string]bool{
+		"192.0.2.1": true, "192.0.2.2": true, "192.0.2.3": true,
+	}}
+	ctrl := newTestController(t, ControllerConfig{
+		ScamperURL:     f.listen(t),
+		DefaultTimeout: 400 * time.Millisecond,
+	})
+
+	start := time.Now()
+	timeout := pingTask("192.0.2.1")
+	timeout.Timeout = 200 * time.Millisecond
+	deadline := pingTask("192.0.2.2")
+	deadline.Deadline = start.Add(200 * time.Millisecond)
+	ctrl.TaskQueue() <- timeout
+	ctrl.TaskQueue() <- deadline
+	ctrl.TaskQueue() <- pingTask("192.0.2.3") // default timeout
+	ctrl.TaskQueue() <- pingTask("192.0.2.4") // answered
+
+	want := map[string]time.Duration{
+		"192.0.2.1": 200 * time.Millisecond,
+		"192.0.2.2": 200 * time.Millisecond,
+		"192.0.2.3": 400 * time.Millisecond,
+	}
+	for i := 0; i < 4; i++ {
+		var task measurement.Task
+		select {
+		case task = <-ctrl.ResultQueue():
+		case <-time.After(5 * time.Second):
+			t.Fatal("tasks did not time out")
+		}
+		elapsed := time.Since(start)
+		limit, timesOut := want[task.Target]
+		if !timesOut {
+			if task.Status != measurement.TASK_COMPLETED {
+				t.Errorf("task towards %s: status %s", task.Target,
+					task.Status)
+			}
+			continue
+		}
+		if task.Status != measurement.TASK_TIMED_OUT ||
+			task.Error != ErrTaskTimedOut.Error() {
+			t.Errorf("task towards %s: status %s (%q)", task.Target,
+				task.Status, task.Error)
+		}
+		if elapsed < limit {
+			t.Errorf("task towards %s timed out after %v, want at least %v",
+				task.Target, elapsed, limit)
+		}
+	}
+
+	// scamper was asked to halt each of them
+	for len(f.haltedTasks()) < 3 {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("halted %v, want 3 tasks", f.haltedTasks())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ctrl.Drain(ctx); err != nil {
+		t.Errorf("Drain() = %v", err)
+	}
+	ctrl.Close()
+}
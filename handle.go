@@ -0,0 +1,88 @@
+package scurry
+
+import (
+	"sync"
+
+	"github.com/alistairking/scurry/measurement"
+)
+
+// Handle to a single task submitted using Controller.Submit. The
+// handle can be used to wait for, retrieve, or cancel the task.
+type TaskHandle struct {
+	ctrl   *Controller
+	userId uint64
+
+	done     chan struct{}
+	doneOnce *sync.Once
+
+	mu   *sync.Mutex
+	task measurement.Task
+	err  error
+}
+
+func newTaskHandle(ctrl *Controller, task measurement.Task) *TaskHandle {
+	return &TaskHandle{
+		ctrl:     ctrl,
+		done:     make(chan struct{}),
+		doneOnce: &sync.Once{},
+		mu:       &sync.Mutex{},
+		task:     task,
+	}
+}
+
+// Returns a channel that is closed once the task has finished (either
+// successfully or not).
+func (h *TaskHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Returns the task. Once Done is closed, the task will have its
+// Result populated (if there is one).
+func (h *TaskHandle) Task() measurement.Task {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.task
+}
+
+// Returns the task result, or nil if the task has not finished, or
+// finished without a result.
+func (h *TaskHandle) Result() measurement.Result {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.task.Result
+}
+
+// Returns the reason that the task finished without a result, or nil
+// if the task has not finished, or finished successfully.
+func (h *TaskHandle) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// Stops waiting for the task. Any result subsequently received from
+// scamper for the task will be discarded.
+func (h *TaskHandle) Cancel() {
+	h.mu.Lock()
+	userId := h.userId
+	h.mu.Unlock()
+	h.ctrl.cancelTask(userId)
+}
+
+// Called by the Controller once the task has been sent to scamper
+func (h *TaskHandle) sent(task measurement.Task) {
+	h.mu.Lock()
+	h.userId = task.UserId
+	h.task = task
+	h.mu.Unlock()
+}
+
+func (h *TaskHandle) finish(task measurement.Task, err error) {
+	h.doneOnce.Do(func() {
+		h.mu.Lock()
+		h.task = task
+		h.err = err
+		h.mu.Unlock()
+		close(h.done)
+	})
+}
@@ -0,0 +1,86 @@
+package measurement
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Helper for rendering typed option structs into scamper command
+// arguments. Options that are unset (zero) or equal to scamper's own
+// default are omitted so that the resulting command is as short as
+// possible.
+type cmdBuilder struct {
+	args []string
+}
+
+func (b *cmdBuilder) flag(f string, set bool) {
+	if set {
+		b.args = append(b.args, "-"+f)
+	}
+}
+
+func (b *cmdBuilder) uint(f string, v uint64, def uint64) {
+	if v == 0 || v == def {
+		return
+	}
+	b.args = append(b.args, "-"+f, strconv.FormatUint(v, 10))
+}
+
+func (b *cmdBuilder) str(f string, v string) {
+	if v == "" {
+		return
+	}
+	b.args = append(b.args, "-"+f, v)
+}
+
+func (b *cmdBuilder) hex(f string, v string) {
+	b.str(f, normalizeHex(v))
+}
+
+func (b *cmdBuilder) String() string {
+	return strings.Join(b.args, " ")
+}
+
+// Strips any "0x" prefix, whitespace and ':' separators from a hex
+// string so that it can be passed to scamper as a single argument.
+func normalizeHex(h string) string {
+	h = strings.TrimSpace(h)
+	if strings.HasPrefix(h, "0x") || strings.HasPrefix(h, "0X") {
+		h = h[2:]
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', ':':
+			return -1
+		}
+		return r
+	}, strings.ToLower(h))
+}
+
+// Checks that a (normalized) hex string is valid for scamper, and
+// optionally that it is no longer than maxBytes bytes.
+func validateHex(name string, h string, maxBytes int) error {
+	h = normalizeHex(h)
+	if len(h)%2 != 0 {
+		return fmt.Errorf("%s must have an even number of hex digits", name)
+	}
+	for _, r := range h {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return fmt.Errorf("%s contains invalid hex character %q", name, r)
+		}
+	}
+	if maxBytes > 0 && len(h)/2 > maxBytes {
+		return fmt.Errorf("%s may be at most %d bytes", name, maxBytes)
+	}
+	return nil
+}
+
+// Checks that a string option can be passed to scamper as a single
+// argument.
+func validateArg(name string, v string) error {
+	if strings.ContainsAny(v, " \t\r\n") {
+		return fmt.Errorf("%s must not contain whitespace", name)
+	}
+	return nil
+}
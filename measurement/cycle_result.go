@@ -0,0 +1,34 @@
+package measurement
+
+import (
+	"time"
+)
+
+// Marks the start ("cycle-start") or end ("cycle-stop") of a
+// measurement cycle. Scamper emits a cycle-start when a client
+// attaches.
+type CycleResult struct {
+	ScResult
+	ListName  string `json:"list_name"`
+	Id        uint32 `json:"id"`
+	Hostname  string `json:"hostname,omitempty"`
+	StartTime int64  `json:"start_time,omitempty"`
+	StopTime  int64  `json:"stop_time,omitempty"`
+}
+
+// Describes the list that a measurement cycle belongs to
+type ListResult struct {
+	ScResult
+	Name    string `json:"list_name"`
+	Id      uint32 `json:"id"`
+	Descr   string `json:"descr,omitempty"`
+	Monitor string `json:"monitor,omitempty"`
+}
+
+func (r CycleResult) Started() time.Time {
+	return time.Unix(r.StartTime, 0)
+}
+
+func (r CycleResult) Stopped() time.Time {
+	return time.Unix(r.StopTime, 0)
+}
@@ -0,0 +1,228 @@
+package measurement
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Represents a scamper "dealias" (alias resolution) task
+//
+// The Task Target for a dealias task is a whitespace-separated list
+// of the addresses to resolve, e.g., "192.0.2.1 192.0.2.2". Which
+// addresses (and how many) are required depends on the method:
+//   - mercator: a single address
+//   - ally, bump: a pair of addresses (or one address in each of two
+//     probe definitions)
+//   - radargun: a set of addresses
+//   - prefixscan: an address and a prefix to scan, e.g.,
+//     "192.0.2.1 192.0.32.10/30"
+//
+// Implements ScCommand
+type Dealias struct {
+	Method      DealiasMethod     `short:"m" default:"mercator" help:"Alias resolution technique to use."`
+	DstPort     uint16            `short:"d" help:"Destination port to use in probes."`
+	Fudge       uint16            `short:"f" help:"Fudge to use when comparing IP-ID values (ally, bump)."`
+	ReplyCount  uint8             `short:"o" help:"Number of replies required for an alias pair (prefixscan)."`
+	ProbeDefs   []DealiasProbeDef `name:"probedef" short:"p" help:"Probe definition(s) to use, in scamper's probedef syntax. The definition may be quoted or the leading dashes omitted, e.g., \"'-P udp -i 192.0.2.1'\" or 'P udp i 192.0.2.1'."`
+	Attempts    uint8             `short:"q" help:"Number of attempts to make with each probe."`
+	WaitRound   uint32            `short:"r" help:"Length of time to wait, in milliseconds, between rounds (radargun)."`
+	SrcPort     uint16            `help:"Source port to use in probes."`
+	TTL         uint8             `help:"TTL value to use for outgoing packets (mercator)."`
+	WaitTimeout uint8             `short:"w" help:"Length of time to wait, in seconds, for a response to a probe."`
+	WaitProbe   uint32            `short:"W" help:"Length of time to wait, in milliseconds, between probes."`
+	Exclude     []string          `short:"x" help:"Addresses to exclude from the prefix scan (prefixscan)."`
+	Options     []DealiasOption   `short:"O" help:"Additional dealias options (inseq, shuffle, nobs)."`
+}
+
+// A scamper dealias probe definition. Renders to (and parses from)
+// scamper's probedef syntax, e.g., "-P tcp-ack-sport -d 80 -i 192.0.2.1"
+type DealiasProbeDef struct {
+	Method  DealiasProbeMethod
+	Addr    string
+	DstPort uint16
+	SrcPort uint16
+	ICMPSum uint16
+	MTU     uint16
+	Size    uint16
+	TTL     uint8
+}
+
+//go:generate enumer -type=DealiasMethod -json -text -linecomment
+type DealiasMethod uint8
+
+const (
+	DEALIAS_MERCATOR   DealiasMethod = iota // mercator
+	DEALIAS_ALLY                            // ally
+	DEALIAS_RADARGUN                        // radargun
+	DEALIAS_PREFIXSCAN                      // prefixscan
+	DEALIAS_BUMP                            // bump
+)
+
+//go:generate enumer -type=DealiasProbeMethod -json -text -linecomment
+type DealiasProbeMethod uint8
+
+const (
+	DEALIAS_PROBE_UDP           DealiasProbeMethod = iota // udp
+	DEALIAS_PROBE_UDP_DPORT                               // udp-dport
+	DEALIAS_PROBE_ICMP_ECHO                               // icmp-echo
+	DEALIAS_PROBE_TCP_ACK                                 // tcp-ack
+	DEALIAS_PROBE_TCP_ACK_SPORT                           // tcp-ack-sport
+	DEALIAS_PROBE_TCP_SYN_SPORT                           // tcp-syn-sport
+)
+
+//go:generate enumer -type=DealiasOption -json -text -linecomment
+type DealiasOption uint8
+
+const (
+	DEALIAS_OPT_INSEQ   DealiasOption = iota // inseq
+	DEALIAS_OPT_SHUFFLE                      // shuffle
+	DEALIAS_OPT_NOBS                         // nobs
+)
+
+func (d Dealias) AsCommand() string {
+	b := &cmdBuilder{}
+	b.uint("d", uint64(d.DstPort), 0)
+	b.uint("f", uint64(d.Fudge), 0)
+	if d.Method != DEALIAS_MERCATOR && d.Method.IsADealiasMethod() {
+		b.str("m", d.Method.String())
+	}
+	b.uint("o", uint64(d.ReplyCount), 0)
+	for _, o := range d.Options {
+		if o.IsADealiasOption() {
+			b.str("O", o.String())
+		}
+	}
+	for _, pd := range d.ProbeDefs {
+		b.str("p", "'"+pd.String()+"'")
+	}
+	b.uint("q", uint64(d.Attempts), 0)
+	b.uint("r", uint64(d.WaitRound), 0)
+	b.uint("s", uint64(d.SrcPort), 0)
+	b.uint("t", uint64(d.TTL), 0)
+	b.uint("w", uint64(d.WaitTimeout), 0)
+	b.uint("W", uint64(d.WaitProbe), 0)
+	for _, addr := range d.Exclude {
+		b.str("x", addr)
+	}
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (d Dealias) Validate() error {
+	if !d.Method.IsADealiasMethod() {
+		return fmt.Errorf("invalid dealias method: %s", d.Method)
+	}
+	for _, o := range d.Options {
+		if !o.IsADealiasOption() {
+			return fmt.Errorf("invalid dealias option: %s", o)
+		}
+	}
+	for _, pd := range d.ProbeDefs {
+		if err := pd.Validate(); err != nil {
+			return err
+		}
+	}
+	switch d.Method {
+	case DEALIAS_ALLY, DEALIAS_BUMP:
+		if len(d.ProbeDefs) > 2 {
+			return fmt.Errorf("%s requires at most two probe definitions",
+				d.Method)
+		}
+	case DEALIAS_MERCATOR, DEALIAS_PREFIXSCAN:
+		if len(d.ProbeDefs) > 1 {
+			return fmt.Errorf("%s requires at most one probe definition",
+				d.Method)
+		}
+	}
+	if len(d.Exclude) > 0 && d.Method != DEALIAS_PREFIXSCAN {
+		return fmt.Errorf("exclude is only valid for prefixscan")
+	}
+	for _, addr := range d.Exclude {
+		if err := validateArg("exclude address", addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pd DealiasProbeDef) String() string {
+	b := &cmdBuilder{}
+	b.uint("c", uint64(pd.ICMPSum), 0)
+	b.uint("d", uint64(pd.DstPort), 0)
+	b.uint("F", uint64(pd.SrcPort), 0)
+	b.str("i", pd.Addr)
+	b.uint("M", uint64(pd.MTU), 0)
+	if pd.Method.IsADealiasProbeMethod() {
+		b.str("P", pd.Method.String())
+	}
+	b.uint("s", uint64(pd.Size), 0)
+	b.uint("t", uint64(pd.TTL), 0)
+	return b.String()
+}
+
+func (pd DealiasProbeDef) Validate() error {
+	if !pd.Method.IsADealiasProbeMethod() {
+		return fmt.Errorf("invalid dealias probe method: %s", pd.Method)
+	}
+	return validateArg("probedef address", pd.Addr)
+}
+
+// Parses a probe definition in scamper's probedef syntax. The
+// definition may be quoted, and the leading dashes on the options may
+// be omitted (e.g., "P udp i 192.0.2.1").
+func DealiasProbeDefString(s string) (DealiasProbeDef, error) {
+	pd := DealiasProbeDef{}
+	fields := strings.Fields(strings.Trim(s, "'\""))
+	if len(fields)%2 != 0 {
+		return pd, fmt.Errorf("malformed probedef: '%s'", s)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		opt, val := "-"+strings.TrimPrefix(fields[i], "-"), fields[i+1]
+		var err error
+		switch opt {
+		case "-P":
+			pd.Method, err = DealiasProbeMethodString(val)
+		case "-i":
+			pd.Addr = val
+		case "-c":
+			pd.ICMPSum, err = parseUint16(val)
+		case "-d":
+			pd.DstPort, err = parseUint16(val)
+		case "-F":
+			pd.SrcPort, err = parseUint16(val)
+		case "-M":
+			pd.MTU, err = parseUint16(val)
+		case "-s":
+			pd.Size, err = parseUint16(val)
+		case "-t":
+			var ttl uint64
+			ttl, err = strconv.ParseUint(val, 10, 8)
+			pd.TTL = uint8(ttl)
+		default:
+			err = fmt.Errorf("unknown option %s", opt)
+		}
+		if err != nil {
+			return pd, fmt.Errorf("malformed probedef '%s': %v", s, err)
+		}
+	}
+	return pd, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for DealiasProbeDef
+func (pd DealiasProbeDef) MarshalText() ([]byte, error) {
+	return []byte(pd.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for DealiasProbeDef
+func (pd *DealiasProbeDef) UnmarshalText(text []byte) error {
+	var err error
+	*pd, err = DealiasProbeDefString(string(text))
+	return err
+}
+
+func parseUint16(s string) (uint16, error) {
+	v, err := strconv.ParseUint(s, 10, 16)
+	return uint16(v), err
+}
@@ -0,0 +1,133 @@
+package measurement
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result of a scamper "dealias" task
+type DealiasResult struct {
+	ScResult
+	// Scamper's names for the alias resolution technique (e.g.,
+	// "mercator") and for its conclusion (e.g., "aliases"). Both
+	// are strings, rather than a DealiasMethod and DealiasVerdict,
+	// so that values added by newer versions of scamper don't stop
+	// the result decoding. See ResolutionMethod and Verdict.
+	Method      string               `json:"method"`
+	Result      string               `json:"result"`
+	Start       ScTime               `json:"start"`
+	WaitProbe   int                  `json:"wait_probe,omitempty"`
+	WaitTimeout int                  `json:"wait_timeout,omitempty"`
+	WaitRound   int                  `json:"wait_round,omitempty"`
+	Attempts    int                  `json:"attempts,omitempty"`
+	Fudge       int                  `json:"fudge,omitempty"`
+	ProbeDefs   []DealiasProbeDefRes `json:"probedefs"`
+	Probes      []DealiasProbe       `json:"probes"`
+}
+
+// A probe definition as reported by scamper in a dealias result
+type DealiasProbeDefRes struct {
+	Id   int    `json:"id"`
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+	TTL  uint8  `json:"ttl"`
+	Size int    `json:"size"`
+	// See ProbeMethod
+	Method  string `json:"method"`
+	SrcPort uint16 `json:"sport,omitempty"`
+	DstPort uint16 `json:"dport,omitempty"`
+	ICMPId  uint16 `json:"icmp_id,omitempty"`
+	ICMPSum uint16 `json:"icmp_csum,omitempty"`
+}
+
+type DealiasProbe struct {
+	ProbeDefId int            `json:"probedef_id"`
+	Seq        int            `json:"seq"`
+	Tx         ScTime         `json:"tx"`
+	IPID       uint32         `json:"ipid"`
+	Replies    []DealiasReply `json:"replies"`
+}
+
+type DealiasReply struct {
+	Src      string `json:"src"`
+	Rx       ScTime `json:"rx"`
+	TTL      uint8  `json:"ttl"`
+	IPID     uint32 `json:"ipid"`
+	Proto    uint8  `json:"proto"`
+	ICMPType *uint8 `json:"icmp_type,omitempty"`
+	ICMPCode *uint8 `json:"icmp_code,omitempty"`
+	TCPFlags *uint8 `json:"tcp_flags,omitempty"`
+}
+
+//go:generate enumer -type=DealiasVerdict -json -text -linecomment
+type DealiasVerdict uint8
+
+const (
+	DEALIAS_RESULT_NONE        DealiasVerdict = iota // none
+	DEALIAS_RESULT_ALIASES                           // aliases
+	DEALIAS_RESULT_NOT_ALIASES                       // not aliases
+	DEALIAS_RESULT_HALTED                            // halted
+	DEALIAS_RESULT_IPID_ECHO                         // ipid echo
+)
+
+func NewDealiasResultFromJson(scJson string) (*DealiasResult, error) {
+	var res DealiasResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r DealiasResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the alias resolution technique as a DealiasMethod, or false
+// if it is one that scurry does not know about.
+func (r DealiasResult) ResolutionMethod() (DealiasMethod, bool) {
+	m, err := DealiasMethodString(r.Method)
+	return m, err == nil
+}
+
+// Returns scamper's conclusion as a DealiasVerdict, or false if it is
+// one that scurry does not know about.
+func (r DealiasResult) Verdict() (DealiasVerdict, bool) {
+	v, err := DealiasVerdictString(r.Result)
+	return v, err == nil
+}
+
+// Returns true if scamper concluded that the addresses are aliases
+func (r DealiasResult) Aliases() bool {
+	return r.Result == DEALIAS_RESULT_ALIASES.String()
+}
+
+// Returns true if scamper concluded that the addresses are not
+// aliases
+func (r DealiasResult) NotAliases() bool {
+	return r.Result == DEALIAS_RESULT_NOT_ALIASES.String()
+}
+
+// Returns the probes sent using the given probe definition
+func (r DealiasResult) ProbesFor(probeDefId int) []DealiasProbe {
+	probes := []DealiasProbe{}
+	for _, p := range r.Probes {
+		if p.ProbeDefId == probeDefId {
+			probes = append(probes, p)
+		}
+	}
+	return probes
+}
+
+// Returns the probe method as a DealiasProbeMethod, or false if it is
+// one that scurry does not know about.
+func (pd DealiasProbeDefRes) ProbeMethod() (DealiasProbeMethod, bool) {
+	m, err := DealiasProbeMethodString(pd.Method)
+	return m, err == nil
+}
+
+// Returns the time between the probe being sent and the reply being
+// received.
+func (r DealiasReply) RTT(probe DealiasProbe) time.Duration {
+	return r.Rx.Time().Sub(probe.Tx.Time())
+}
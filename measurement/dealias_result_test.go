@@ -0,0 +1,73 @@
+package measurement
+
+import "testing"
+
+func decodeDealias(t *testing.T, scJson string) *DealiasResult {
+	t.Helper()
+	res, err := NewResultFromJson(scJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := res.(*DealiasResult)
+	if !ok {
+		t.Fatalf("decoded as %T, want *DealiasResult", res)
+	}
+	return d
+}
+
+func TestDealiasResult(t *testing.T) {
+	res := decodeDealias(t, `{"type":"dealias", "version":"0.2", `+
+		`"userid":5, "method":"ally", "result":"aliases", `+
+		`"probedefs":[{"id":0, "src":"192.0.2.10", "dst":"192.0.2.1", `+
+		`"ttl":255, "size":28, "method":"udp", "dport":33435}, `+
+		`{"id":1, "src":"192.0.2.10", "dst":"192.0.2.2", `+
+		`"ttl":255, "size":28, "method":"udp", "dport":33435}], `+
+		`"probes":[{"probedef_id":0, "seq":0, "ipid":100, "replies":[]}, `+
+		`{"probedef_id":1, "seq":1, "ipid":101, "replies":[]}, `+
+		`{"probedef_id":0, "seq":2, "ipid":102, "replies":[]}]}`)
+
+	if m, ok := res.ResolutionMethod(); !ok || m != DEALIAS_ALLY {
+		t.Errorf("ResolutionMethod() = %v, %v", m, ok)
+	}
+	if v, ok := res.Verdict(); !ok || v != DEALIAS_RESULT_ALIASES {
+		t.Errorf("Verdict() = %v, %v", v, ok)
+	}
+	if !res.Aliases() || res.NotAliases() {
+		t.Errorf("Aliases() = %v, NotAliases() = %v",
+			res.Aliases(), res.NotAliases())
+	}
+	if len(res.ProbeDefs) != 2 {
+		t.Fatalf("got %d probe definitions, want 2", len(res.ProbeDefs))
+	}
+	if m, ok := res.ProbeDefs[1].ProbeMethod(); !ok || m != DEALIAS_PROBE_UDP {
+		t.Errorf("ProbeMethod() = %v, %v", m, ok)
+	}
+	if got := res.ProbesFor(0); len(got) != 2 || got[1].Seq != 2 {
+		t.Errorf("ProbesFor(0) = %+v", got)
+	}
+}
+
+// Methods and verdicts that scurry does not know about must not stop
+// the result from decoding
+func TestDealiasResultUnknownValues(t *testing.T) {
+	res := decodeDealias(t, `{"type":"dealias", "method":"midar", `+
+		`"result":"maybe", "probedefs":[{"id":0, "dst":"192.0.2.1", `+
+		`"method":"icmp-time"}], "probes":[]}`)
+
+	if res.Method != "midar" || res.Result != "maybe" ||
+		len(res.ProbeDefs) != 1 || res.ProbeDefs[0].Method != "icmp-time" {
+		t.Errorf("bad result: %+v", res)
+	}
+	if _, ok := res.ResolutionMethod(); ok {
+		t.Errorf("ResolutionMethod() recognised an unknown method")
+	}
+	if _, ok := res.Verdict(); ok {
+		t.Errorf("Verdict() recognised an unknown verdict")
+	}
+	if _, ok := res.ProbeDefs[0].ProbeMethod(); ok {
+		t.Errorf("ProbeMethod() recognised an unknown method")
+	}
+	if res.Aliases() || res.NotAliases() {
+		t.Errorf("unknown verdict treated as a conclusion")
+	}
+}
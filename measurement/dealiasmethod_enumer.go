@@ -0,0 +1,82 @@
+// Code generated by "enumer -type=DealiasMethod -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _DealiasMethodName = "mercatorallyradargunprefixscanbump"
+
+var _DealiasMethodIndex = [...]uint8{0, 8, 12, 20, 30, 34}
+
+func (i DealiasMethod) String() string {
+	if i >= DealiasMethod(len(_DealiasMethodIndex)-1) {
+		return fmt.Sprintf("DealiasMethod(%d)", i)
+	}
+	return _DealiasMethodName[_DealiasMethodIndex[i]:_DealiasMethodIndex[i+1]]
+}
+
+var _DealiasMethodValues = []DealiasMethod{0, 1, 2, 3, 4}
+
+var _DealiasMethodNameToValueMap = map[string]DealiasMethod{
+	_DealiasMethodName[0:8]:   0,
+	_DealiasMethodName[8:12]:  1,
+	_DealiasMethodName[12:20]: 2,
+	_DealiasMethodName[20:30]: 3,
+	_DealiasMethodName[30:34]: 4,
+}
+
+// DealiasMethodString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func DealiasMethodString(s string) (DealiasMethod, error) {
+	if val, ok := _DealiasMethodNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to DealiasMethod values", s)
+}
+
+// DealiasMethodValues returns all values of the enum
+func DealiasMethodValues() []DealiasMethod {
+	return _DealiasMethodValues
+}
+
+// IsADealiasMethod returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i DealiasMethod) IsADealiasMethod() bool {
+	for _, v := range _DealiasMethodValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for DealiasMethod
+func (i DealiasMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for DealiasMethod
+func (i *DealiasMethod) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("DealiasMethod should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = DealiasMethodString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for DealiasMethod
+func (i DealiasMethod) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for DealiasMethod
+func (i *DealiasMethod) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = DealiasMethodString(string(text))
+	return err
+}
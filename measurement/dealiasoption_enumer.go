@@ -0,0 +1,80 @@
+// Code generated by "enumer -type=DealiasOption -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _DealiasOptionName = "inseqshufflenobs"
+
+var _DealiasOptionIndex = [...]uint8{0, 5, 12, 16}
+
+func (i DealiasOption) String() string {
+	if i >= DealiasOption(len(_DealiasOptionIndex)-1) {
+		return fmt.Sprintf("DealiasOption(%d)", i)
+	}
+	return _DealiasOptionName[_DealiasOptionIndex[i]:_DealiasOptionIndex[i+1]]
+}
+
+var _DealiasOptionValues = []DealiasOption{0, 1, 2}
+
+var _DealiasOptionNameToValueMap = map[string]DealiasOption{
+	_DealiasOptionName[0:5]:   0,
+	_DealiasOptionName[5:12]:  1,
+	_DealiasOptionName[12:16]: 2,
+}
+
+// DealiasOptionString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func DealiasOptionString(s string) (DealiasOption, error) {
+	if val, ok := _DealiasOptionNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to DealiasOption values", s)
+}
+
+// DealiasOptionValues returns all values of the enum
+func DealiasOptionValues() []DealiasOption {
+	return _DealiasOptionValues
+}
+
+// IsADealiasOption returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i DealiasOption) IsADealiasOption() bool {
+	for _, v := range _DealiasOptionValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for DealiasOption
+func (i DealiasOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for DealiasOption
+func (i *DealiasOption) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("DealiasOption should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = DealiasOptionString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for DealiasOption
+func (i DealiasOption) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for DealiasOption
+func (i *DealiasOption) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = DealiasOptionString(string(text))
+	return err
+}
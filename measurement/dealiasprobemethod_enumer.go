@@ -0,0 +1,83 @@
+// Code generated by "enumer -type=DealiasProbeMethod -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _DealiasProbeMethodName = "udpudp-dporticmp-echotcp-acktcp-ack-sporttcp-syn-sport"
+
+var _DealiasProbeMethodIndex = [...]uint8{0, 3, 12, 21, 28, 41, 54}
+
+func (i DealiasProbeMethod) String() string {
+	if i >= DealiasProbeMethod(len(_DealiasProbeMethodIndex)-1) {
+		return fmt.Sprintf("DealiasProbeMethod(%d)", i)
+	}
+	return _DealiasProbeMethodName[_DealiasProbeMethodIndex[i]:_DealiasProbeMethodIndex[i+1]]
+}
+
+var _DealiasProbeMethodValues = []DealiasProbeMethod{0, 1, 2, 3, 4, 5}
+
+var _DealiasProbeMethodNameToValueMap = map[string]DealiasProbeMethod{
+	_DealiasProbeMethodName[0:3]:   0,
+	_DealiasProbeMethodName[3:12]:  1,
+	_DealiasProbeMethodName[12:21]: 2,
+	_DealiasProbeMethodName[21:28]: 3,
+	_DealiasProbeMethodName[28:41]: 4,
+	_DealiasProbeMethodName[41:54]: 5,
+}
+
+// DealiasProbeMethodString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func DealiasProbeMethodString(s string) (DealiasProbeMethod, error) {
+	if val, ok := _DealiasProbeMethodNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to DealiasProbeMethod values", s)
+}
+
+// DealiasProbeMethodValues returns all values of the enum
+func DealiasProbeMethodValues() []DealiasProbeMethod {
+	return _DealiasProbeMethodValues
+}
+
+// IsADealiasProbeMethod returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i DealiasProbeMethod) IsADealiasProbeMethod() bool {
+	for _, v := range _DealiasProbeMethodValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for DealiasProbeMethod
+func (i DealiasProbeMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for DealiasProbeMethod
+func (i *DealiasProbeMethod) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("DealiasProbeMethod should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = DealiasProbeMethodString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for DealiasProbeMethod
+func (i DealiasProbeMethod) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for DealiasProbeMethod
+func (i *DealiasProbeMethod) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = DealiasProbeMethodString(string(text))
+	return err
+}
@@ -0,0 +1,82 @@
+// Code generated by "enumer -type=DealiasVerdict -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _DealiasVerdictName = "nonealiasesnot aliaseshaltedipid echo"
+
+var _DealiasVerdictIndex = [...]uint8{0, 4, 11, 22, 28, 37}
+
+func (i DealiasVerdict) String() string {
+	if i >= DealiasVerdict(len(_DealiasVerdictIndex)-1) {
+		return fmt.Sprintf("DealiasVerdict(%d)", i)
+	}
+	return _DealiasVerdictName[_DealiasVerdictIndex[i]:_DealiasVerdictIndex[i+1]]
+}
+
+var _DealiasVerdictValues = []DealiasVerdict{0, 1, 2, 3, 4}
+
+var _DealiasVerdictNameToValueMap = map[string]DealiasVerdict{
+	_DealiasVerdictName[0:4]:   0,
+	_DealiasVerdictName[4:11]:  1,
+	_DealiasVerdictName[11:22]: 2,
+	_DealiasVerdictName[22:28]: 3,
+	_DealiasVerdictName[28:37]: 4,
+}
+
+// DealiasVerdictString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func DealiasVerdictString(s string) (DealiasVerdict, error) {
+	if val, ok := _DealiasVerdictNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to DealiasVerdict values", s)
+}
+
+// DealiasVerdictValues returns all values of the enum
+func DealiasVerdictValues() []DealiasVerdict {
+	return _DealiasVerdictValues
+}
+
+// IsADealiasVerdict returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i DealiasVerdict) IsADealiasVerdict() bool {
+	for _, v := range _DealiasVerdictValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for DealiasVerdict
+func (i DealiasVerdict) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for DealiasVerdict
+func (i *DealiasVerdict) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("DealiasVerdict should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = DealiasVerdictString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for DealiasVerdict
+func (i DealiasVerdict) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for DealiasVerdict
+func (i *DealiasVerdict) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = DealiasVerdictString(string(text))
+	return err
+}
@@ -0,0 +1,71 @@
+package measurement
+
+import (
+	"fmt"
+)
+
+// Represents a scamper "host" (DNS query) task
+//
+// The Task Target for a host task is the name to query (or the
+// address, for PTR queries).
+//
+// Implements ScCommand
+type Host struct {
+	QType     HostQType  `default:"a" help:"Type of DNS query to issue."`
+	QClass    HostQClass `short:"c" default:"in" help:"Class of DNS query to issue."`
+	Server    string     `help:"Address of the DNS server to query (defaults to the system resolver)."`
+	NoRecurse bool       `short:"r" help:"Do not set the recursion desired flag."`
+	Retries   uint8      `short:"R" help:"Number of times to retry the query if no response is received."`
+	TCP       bool       `short:"T" help:"Use TCP rather than UDP."`
+	Wait      uint16     `short:"W" help:"Length of time to wait, in milliseconds, for a response."`
+}
+
+//go:generate enumer -type=HostQType -json -text -linecomment
+type HostQType uint8
+
+const (
+	HOST_QTYPE_A     HostQType = iota // a
+	HOST_QTYPE_AAAA                   // aaaa
+	HOST_QTYPE_PTR                    // ptr
+	HOST_QTYPE_MX                     // mx
+	HOST_QTYPE_NS                     // ns
+	HOST_QTYPE_SOA                    // soa
+	HOST_QTYPE_TXT                    // txt
+	HOST_QTYPE_CNAME                  // cname
+)
+
+//go:generate enumer -type=HostQClass -json -text -linecomment
+type HostQClass uint8
+
+const (
+	HOST_QCLASS_IN HostQClass = iota // in
+	HOST_QCLASS_CH                   // ch
+)
+
+func (h Host) AsCommand() string {
+	b := &cmdBuilder{}
+	b.flag("r", h.NoRecurse)
+	b.flag("T", h.TCP)
+	if h.QClass != HOST_QCLASS_IN && h.QClass.IsAHostQClass() {
+		b.str("c", h.QClass.String())
+	}
+	b.uint("R", uint64(h.Retries), 0)
+	b.str("s", h.Server)
+	if h.QType != HOST_QTYPE_A && h.QType.IsAHostQType() {
+		b.str("t", h.QType.String())
+	}
+	b.uint("W", uint64(h.Wait), 0)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (h Host) Validate() error {
+	if !h.QType.IsAHostQType() {
+		return fmt.Errorf("invalid host query type: %s", h.QType)
+	}
+	if !h.QClass.IsAHostQClass() {
+		return fmt.Errorf("invalid host query class: %s", h.QClass)
+	}
+	return validateArg("server", h.Server)
+}
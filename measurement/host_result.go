@@ -0,0 +1,101 @@
+package measurement
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Result of a scamper "host" task
+type HostResult struct {
+	ScResult
+	Src     string      `json:"src"`
+	Dst     string      `json:"dst"` // the DNS server
+	Start   ScTime      `json:"start"`
+	Wait    int         `json:"wait,omitempty"`
+	Retries int         `json:"retries,omitempty"`
+	QType   string      `json:"qtype"`
+	QClass  string      `json:"qclass"`
+	QName   string      `json:"qname"`
+	QCount  int         `json:"qcount"`
+	Queries []HostQuery `json:"queries"`
+}
+
+// A single DNS query (attempt) and its response
+type HostQuery struct {
+	Id         uint16   `json:"id"`
+	Tx         ScTime   `json:"tx"`
+	Rx         *ScTime  `json:"rx,omitempty"`
+	RCode      int      `json:"rcode"`
+	AnCount    int      `json:"ancount"`
+	NsCount    int      `json:"nscount"`
+	ArCount    int      `json:"arcount"`
+	Answers    []HostRR `json:"an,omitempty"`
+	Authority  []HostRR `json:"ns,omitempty"`
+	Additional []HostRR `json:"ar,omitempty"`
+}
+
+// A DNS resource record. Which of the data fields are populated
+// depends on the record type.
+type HostRR struct {
+	Name    string   `json:"name"`
+	Class   string   `json:"class"`
+	Type    string   `json:"type"`
+	TTL     uint32   `json:"ttl"`
+	Address string   `json:"address,omitempty"` // A, AAAA
+	Ptr     string   `json:"ptr,omitempty"`     // PTR
+	Ns      string   `json:"ns,omitempty"`      // NS
+	Cname   string   `json:"cname,omitempty"`   // CNAME
+	Pref    *uint16  `json:"pref,omitempty"`    // MX
+	Exch    string   `json:"exch,omitempty"`    // MX
+	Txt     []string `json:"txt,omitempty"`     // TXT
+}
+
+func NewHostResultFromJson(scJson string) (*HostResult, error) {
+	var res HostResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r HostResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the answer records from the first query that received a
+// response.
+func (r HostResult) Answers() []HostRR {
+	for _, q := range r.Queries {
+		if q.Rx != nil {
+			return q.Answers
+		}
+	}
+	return []HostRR{}
+}
+
+// Returns the addresses in the A and AAAA answer records.
+func (r HostResult) Addresses() []string {
+	addrs := []string{}
+	for _, rr := range r.Answers() {
+		t := strings.ToUpper(rr.Type)
+		if (t == "A" || t == "AAAA") && rr.Address != "" {
+			addrs = append(addrs, rr.Address)
+		}
+	}
+	return addrs
+}
+
+// Returns the time taken for the response to arrive, or 0 if no
+// response was received.
+func (q HostQuery) RTT() time.Duration {
+	if q.Rx == nil {
+		return 0
+	}
+	return q.Rx.Time().Sub(q.Tx.Time())
+}
+
+func (rr HostRR) TTLDuration() time.Duration {
+	return time.Duration(rr.TTL) * time.Second
+}
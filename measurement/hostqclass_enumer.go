@@ -0,0 +1,79 @@
+// Code generated by "enumer -type=HostQClass -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _HostQClassName = "inch"
+
+var _HostQClassIndex = [...]uint8{0, 2, 4}
+
+func (i HostQClass) String() string {
+	if i >= HostQClass(len(_HostQClassIndex)-1) {
+		return fmt.Sprintf("HostQClass(%d)", i)
+	}
+	return _HostQClassName[_HostQClassIndex[i]:_HostQClassIndex[i+1]]
+}
+
+var _HostQClassValues = []HostQClass{0, 1}
+
+var _HostQClassNameToValueMap = map[string]HostQClass{
+	_HostQClassName[0:2]: 0,
+	_HostQClassName[2:4]: 1,
+}
+
+// HostQClassString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func HostQClassString(s string) (HostQClass, error) {
+	if val, ok := _HostQClassNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to HostQClass values", s)
+}
+
+// HostQClassValues returns all values of the enum
+func HostQClassValues() []HostQClass {
+	return _HostQClassValues
+}
+
+// IsAHostQClass returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i HostQClass) IsAHostQClass() bool {
+	for _, v := range _HostQClassValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for HostQClass
+func (i HostQClass) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for HostQClass
+func (i *HostQClass) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("HostQClass should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = HostQClassString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for HostQClass
+func (i HostQClass) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for HostQClass
+func (i *HostQClass) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = HostQClassString(string(text))
+	return err
+}
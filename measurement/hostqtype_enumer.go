@@ -0,0 +1,85 @@
+// Code generated by "enumer -type=HostQType -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _HostQTypeName = "aaaaaptrmxnssoatxtcname"
+
+var _HostQTypeIndex = [...]uint8{0, 1, 5, 8, 10, 12, 15, 18, 23}
+
+func (i HostQType) String() string {
+	if i >= HostQType(len(_HostQTypeIndex)-1) {
+		return fmt.Sprintf("HostQType(%d)", i)
+	}
+	return _HostQTypeName[_HostQTypeIndex[i]:_HostQTypeIndex[i+1]]
+}
+
+var _HostQTypeValues = []HostQType{0, 1, 2, 3, 4, 5, 6, 7}
+
+var _HostQTypeNameToValueMap = map[string]HostQType{
+	_HostQTypeName[0:1]:   0,
+	_HostQTypeName[1:5]:   1,
+	_HostQTypeName[5:8]:   2,
+	_HostQTypeName[8:10]:  3,
+	_HostQTypeName[10:12]: 4,
+	_HostQTypeName[12:15]: 5,
+	_HostQTypeName[15:18]: 6,
+	_HostQTypeName[18:23]: 7,
+}
+
+// HostQTypeString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func HostQTypeString(s string) (HostQType, error) {
+	if val, ok := _HostQTypeNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to HostQType values", s)
+}
+
+// HostQTypeValues returns all values of the enum
+func HostQTypeValues() []HostQType {
+	return _HostQTypeValues
+}
+
+// IsAHostQType returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i HostQType) IsAHostQType() bool {
+	for _, v := range _HostQTypeValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for HostQType
+func (i HostQType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for HostQType
+func (i *HostQType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("HostQType should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = HostQTypeString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for HostQType
+func (i HostQType) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for HostQType
+func (i *HostQType) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = HostQTypeString(string(text))
+	return err
+}
@@ -0,0 +1,55 @@
+package measurement
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Represents a scamper "http" task
+//
+// The Task Target for an http task is the address of the server to
+// connect to. The URL (which may be http or https) is given in the
+// options.
+//
+// Implements ScCommand
+type Http struct {
+	URL      string   `short:"u" required:"" help:"URL to fetch. https URLs are fetched using TLS."`
+	Headers  []string `short:"H" help:"Additional headers to include in the request, e.g., 'Accept: text/html'."`
+	Insecure bool     `help:"Do not verify the server's TLS certificate (-O insecure)."`
+	Limit    uint32   `short:"l" help:"Maximum number of bytes of the response to receive."`
+	MaxTime  uint16   `short:"m" help:"Maximum length of time, in seconds, to allow the fetch to take."`
+	SrcAddr  string   `short:"S" help:"Source address to use."`
+}
+
+func (h Http) AsCommand() string {
+	b := &cmdBuilder{}
+	for _, hdr := range h.Headers {
+		b.str("H", "'"+hdr+"'")
+	}
+	b.uint("l", uint64(h.Limit), 0)
+	b.uint("m", uint64(h.MaxTime), 0)
+	if h.Insecure {
+		b.str("O", "insecure")
+	}
+	b.str("S", h.SrcAddr)
+	b.str("u", h.URL)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (h Http) Validate() error {
+	if !strings.HasPrefix(h.URL, "http://") &&
+		!strings.HasPrefix(h.URL, "https://") {
+		return fmt.Errorf("http URL must be an http or https URL")
+	}
+	if err := validateArg("URL", h.URL); err != nil {
+		return err
+	}
+	for _, hdr := range h.Headers {
+		if strings.ContainsAny(hdr, "'\r\n") || !strings.Contains(hdr, ":") {
+			return fmt.Errorf("malformed http header: %s", hdr)
+		}
+	}
+	return validateArg("source address", h.SrcAddr)
+}
@@ -0,0 +1,75 @@
+package measurement
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result of a scamper "http" task
+type HttpResult struct {
+	ScResult
+	Src        string            `json:"src"`
+	Dst        string            `json:"dst"`
+	SrcPort    uint16            `json:"sport"`
+	DstPort    uint16            `json:"dport"`
+	Start      ScTime            `json:"start"`
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Bufs       []HttpBuf         `json:"bufs,omitempty"`
+}
+
+// A chunk of data sent or received during an http task
+type HttpBuf struct {
+	Dir  string `json:"dir"`  // "tx" or "rx"
+	Type string `json:"type"` // e.g., "hdr", "data", "tls"
+	Time ScTime `json:"tm"`
+	Len  int    `json:"len"`
+	Data string `json:"data,omitempty"` // hex
+}
+
+func NewHttpResultFromJson(scJson string) (*HttpResult, error) {
+	var res HttpResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r HttpResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the time between the start of the task and the first
+// response bytes being received, or 0 if no response was received.
+func (r HttpResult) TimeToFirstByte() time.Duration {
+	for _, b := range r.Bufs {
+		if b.Dir == "rx" && b.Type != "tls" {
+			return b.Time.Time().Sub(r.Start.Time())
+		}
+	}
+	return 0
+}
+
+// Returns the time between the start of the task and the last data
+// being received, or 0 if no response was received.
+func (r HttpResult) Duration() time.Duration {
+	for i := len(r.Bufs) - 1; i >= 0; i-- {
+		if r.Bufs[i].Dir == "rx" {
+			return r.Bufs[i].Time.Time().Sub(r.Start.Time())
+		}
+	}
+	return 0
+}
+
+// Returns the total number of bytes received.
+func (r HttpResult) RxBytes() int {
+	n := 0
+	for _, b := range r.Bufs {
+		if b.Dir == "rx" {
+			n += b.Len
+		}
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+// Code generated by "enumer -type=JsonFormat -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _JsonFormatName = "tasktask-rawscamper"
+
+var _JsonFormatIndex = [...]uint8{0, 4, 12, 19}
+
+func (i JsonFormat) String() string {
+	if i >= JsonFormat(len(_JsonFormatIndex)-1) {
+		return fmt.Sprintf("JsonFormat(%d)", i)
+	}
+	return _JsonFormatName[_JsonFormatIndex[i]:_JsonFormatIndex[i+1]]
+}
+
+var _JsonFormatValues = []JsonFormat{0, 1, 2}
+
+var _JsonFormatNameToValueMap = map[string]JsonFormat{
+	_JsonFormatName[0:4]:   0,
+	_JsonFormatName[4:12]:  1,
+	_JsonFormatName[12:19]: 2,
+}
+
+// JsonFormatString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func JsonFormatString(s string) (JsonFormat, error) {
+	if val, ok := _JsonFormatNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to JsonFormat values", s)
+}
+
+// JsonFormatValues returns all values of the enum
+func JsonFormatValues() []JsonFormat {
+	return _JsonFormatValues
+}
+
+// IsAJsonFormat returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i JsonFormat) IsAJsonFormat() bool {
+	for _, v := range _JsonFormatValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for JsonFormat
+func (i JsonFormat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for JsonFormat
+func (i *JsonFormat) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("JsonFormat should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = JsonFormatString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for JsonFormat
+func (i JsonFormat) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for JsonFormat
+func (i *JsonFormat) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = JsonFormatString(string(text))
+	return err
+}
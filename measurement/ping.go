@@ -1,5 +1,9 @@
 package measurement
 
+import (
+	"fmt"
+)
+
 // Represents a scamper "ping" task
 //
 // Implements ScCommand
@@ -14,13 +18,20 @@ type Ping struct {
 	TTL        uint8  `short:"m" default:"64" help:"TTL value to use for outgoing packets."`
 	MTU        uint16 `short:"M" help:"Pseudo MTU value. If the response packet is larger than the pseudo MTU, an ICMP packet too big (PTB) message is sent."`
 	ReplyCount uint16 `short:"o" help:"Number of replies required at which time probing may cease. By default, all probes are sent"`
-	// TODO: -O options
+	// -O options
+	DL          bool       `help:"Use datalink-level timestamps for sent probes (-O dl)."`
+	DLTx        bool       `help:"Send probes at the datalink level (-O dltx)."`
+	NoSrc       bool       `help:"Do not embed the source address in probe payloads (-O nosrc)."`
+	Raw         bool       `help:"Send probes using a raw socket (-O raw)."`
+	SockRx      bool       `help:"Receive replies using a socket rather than the datalink (-O sockrx)."`
+	Spoof       bool       `help:"Spoof the source address given by src-addr (-O spoof)."`
+	TBT         bool       `help:"Use the too-big trick to elicit fragmented replies. Requires mtu (-O tbt)."`
 	Pattern     string     `short:"p" help:"Pattern, in hex, to use in probes. Up to 16 bytes may be specified. By default, each probe’s bytes are zeroed."`
 	Method      PingMethod `short:"P" default:"icmp-echo" help:"Type of ping packets to send."`
 	RouterAddr  string     `short:"r" help:"IP address of the router to use."`
 	RecordRoute bool       `short:"R" help:"Specifies that the record route IP option should be used."`
 	Size        uint16     `short:"s" help:"Size of the probes to send. The probe size includes the length of the IP and ICMP headers. By default, a probe size of 84 bytes is used for IPv4 pings, and 56 bytes for IPv6 pings."`
-	SrcAddr     string     `short:"S" help:"Source address to use in probes. The address can be spoofed if Options.Spoof is set."`
+	SrcAddr     string     `short:"S" help:"Source address to use in probes. The address can be spoofed if Spoof is set."`
 	Timestamp   string     `short:"T" help:"Specifies that an IP timestamp option be included."`
 	Timeout     uint8      `short:"W" default:"1" help:"How long to wait for responses after the last ping is sent."`
 }
@@ -40,7 +51,85 @@ const (
 	UDP_DPORT                       // udp-dport
 )
 
+// Scamper's defaults for the ping options that have them. Options
+// set to these values are omitted from the command.
+const (
+	pingDefProbeCount = 4
+	pingDefWait       = 1
+	pingDefTTL        = 64
+	pingDefTimeout    = 1
+)
+
 func (p Ping) AsCommand() string {
-	// TODO
-	return ""
+	b := &cmdBuilder{}
+	b.uint("A", uint64(p.TCPAck), 0)
+	b.hex("B", p.Payload)
+	b.uint("c", uint64(p.ProbeCount), pingDefProbeCount)
+	b.uint("C", uint64(p.ICMPSum), 0)
+	b.uint("d", uint64(p.DstPort), 0)
+	b.uint("F", uint64(p.SrcPort), 0)
+	b.uint("i", uint64(p.Wait), pingDefWait)
+	b.uint("m", uint64(p.TTL), pingDefTTL)
+	b.uint("M", uint64(p.MTU), 0)
+	b.uint("o", uint64(p.ReplyCount), 0)
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"dl", p.DL},
+		{"dltx", p.DLTx},
+		{"nosrc", p.NoSrc},
+		{"raw", p.Raw},
+		{"sockrx", p.SockRx},
+		{"spoof", p.Spoof},
+		{"tbt", p.TBT},
+	} {
+		if o.set {
+			b.str("O", o.name)
+		}
+	}
+	b.hex("p", p.Pattern)
+	if p.Method != ICMP_ECHO && p.Method.IsAPingMethod() {
+		b.str("P", p.Method.String())
+	}
+	b.str("r", p.RouterAddr)
+	b.flag("R", p.RecordRoute)
+	b.uint("s", uint64(p.Size), 0)
+	b.str("S", p.SrcAddr)
+	b.str("T", p.Timestamp)
+	b.uint("W", uint64(p.Timeout), pingDefTimeout)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (p Ping) Validate() error {
+	if !p.Method.IsAPingMethod() {
+		return fmt.Errorf("invalid ping method: %s", p.Method)
+	}
+	if p.Spoof && p.SrcAddr == "" {
+		return fmt.Errorf("spoof requires a source address")
+	}
+	if p.TBT && p.MTU == 0 {
+		return fmt.Errorf("tbt requires an MTU")
+	}
+	if p.Raw && p.DLTx {
+		return fmt.Errorf("raw and dltx are mutually exclusive")
+	}
+	if p.DL && p.SockRx {
+		return fmt.Errorf("dl and sockrx are mutually exclusive")
+	}
+	if err := validateHex("payload", p.Payload, 0); err != nil {
+		return err
+	}
+	if err := validateHex("pattern", p.Pattern, 16); err != nil {
+		return err
+	}
+	if err := validateArg("router address", p.RouterAddr); err != nil {
+		return err
+	}
+	if err := validateArg("source address", p.SrcAddr); err != nil {
+		return err
+	}
+	return validateArg("timestamp", p.Timestamp)
 }
@@ -0,0 +1,129 @@
+package measurement
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result of a scamper "ping" task
+type PingResult struct {
+	ScResult
+	// Scamper's name for the probe method (e.g., "icmp-echo"). This
+	// is kept as a string, rather than a PingMethod, so that
+	// results using methods added by newer versions of scamper
+	// still decode.
+	Method     string      `json:"method"`
+	Src        string      `json:"src"`
+	Dst        string      `json:"dst"`
+	Start      ScTime      `json:"start"`
+	PingSent   int         `json:"ping_sent"`
+	ProbeSize  int         `json:"probe_size"`
+	TTL        uint8       `json:"ttl"`
+	Wait       int         `json:"wait"`
+	Timeout    int         `json:"timeout"`
+	Flags      []string    `json:"flags,omitempty"`
+	Responses  []PingReply `json:"responses"`
+	Statistics *PingStats  `json:"statistics,omitempty"`
+}
+
+// A single reply received in response to a ping probe
+type PingReply struct {
+	From      string   `json:"from"`
+	Seq       int      `json:"seq"`
+	ReplySize int      `json:"reply_size"`
+	ReplyTTL  uint8    `json:"reply_ttl"`
+	Proto     string   `json:"reply_proto"`
+	Tx        ScTime   `json:"tx"`
+	Rx        *ScTime  `json:"rx,omitempty"`
+	RTT       float64  `json:"rtt"` // milliseconds
+	ProbeIPID uint16   `json:"probe_ipid,omitempty"`
+	ReplyIPID uint16   `json:"reply_ipid,omitempty"`
+	ICMPType  *uint8   `json:"icmp_type,omitempty"`
+	ICMPCode  *uint8   `json:"icmp_code,omitempty"`
+	TCPFlags  *uint8   `json:"tcp_flags,omitempty"`
+	Flags     []string `json:"flags,omitempty"`
+}
+
+// Summary statistics for a ping task. RTT values are in milliseconds.
+type PingStats struct {
+	Replies int     `json:"replies"`
+	Loss    int     `json:"loss"`
+	Min     float64 `json:"min"`
+	Avg     float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	StdDev  float64 `json:"stddev"`
+}
+
+func NewPingResultFromJson(scJson string) (*PingResult, error) {
+	var res PingResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r PingResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the replies that were received for a probe that had
+// already been replied to.
+func (r PingResult) Duplicates() []PingReply {
+	seen := map[int]bool{}
+	dups := []PingReply{}
+	for _, resp := range r.Responses {
+		if seen[resp.Seq] {
+			dups = append(dups, resp)
+		}
+		seen[resp.Seq] = true
+	}
+	return dups
+}
+
+// Returns the RTTs of all replies.
+func (r PingResult) RTTs() []time.Duration {
+	rtts := make([]time.Duration, 0, len(r.Responses))
+	for _, resp := range r.Responses {
+		rtts = append(rtts, resp.RTTDuration())
+	}
+	return rtts
+}
+
+func (r PingReply) RTTDuration() time.Duration {
+	return msToDuration(r.RTT)
+}
+
+// Returns the time the reply was received. If scamper did not report
+// an rx timestamp, this is computed from the tx time and RTT.
+func (r PingReply) RxTime() time.Time {
+	if r.Rx != nil {
+		return r.Rx.Time()
+	}
+	return r.Tx.Time().Add(r.RTTDuration())
+}
+
+func (r PingReply) HasFlag(flag string) bool {
+	for _, f := range r.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (s PingStats) MinRTT() time.Duration {
+	return msToDuration(s.Min)
+}
+
+func (s PingStats) AvgRTT() time.Duration {
+	return msToDuration(s.Avg)
+}
+
+func (s PingStats) MaxRTT() time.Duration {
+	return msToDuration(s.Max)
+}
+
+func (s PingStats) StdDevRTT() time.Duration {
+	return msToDuration(s.StdDev)
+}
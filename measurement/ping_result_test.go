@@ -0,0 +1,159 @@
+package measurement
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readSample(t *testing.T, name string) string {
+	t.Helper()
+	d, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(d))
+}
+
+func decodePingSample(t *testing.T, name string) *PingResult {
+	t.Helper()
+	res, err := NewResultFromJson(readSample(t, name))
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+	ping, ok := res.(*PingResult)
+	if !ok {
+		t.Fatalf("decoded %s as %T, want *PingResult", name, res)
+	}
+	return ping
+}
+
+func TestPingResultIcmp(t *testing.T) {
+	res := decodePingSample(t, "ping_icmp.json")
+
+	if res.ResultType() != "ping" || res.ResultUserId() != 7 {
+		t.Errorf("bad header: type %q userid %d",
+			res.ResultType(), res.ResultUserId())
+	}
+	if res.Method != "icmp-echo" || res.Src != "192.0.2.10" ||
+		res.Dst != "8.8.8.8" || res.PingSent != 3 ||
+		res.ProbeSize != 84 || res.TTL != 64 {
+		t.Errorf("bad fields: %+v", res)
+	}
+	if got := res.Start.Time(); !got.Equal(time.Unix(1700000000, 123456000)) {
+		t.Errorf("Start = %v", got)
+	}
+	if string(res.Raw()) != readSample(t, "ping_icmp.json") {
+		t.Errorf("Raw() does not match the input")
+	}
+
+	if len(res.Responses) != 3 {
+		t.Fatalf("got %d responses, want 3", len(res.Responses))
+	}
+	r := res.Responses[0]
+	if r.From != "8.8.8.8" || r.Seq != 0 || r.ReplySize != 84 ||
+		r.ReplyTTL != 117 || r.Proto != "icmp" || r.ProbeIPID != 4660 {
+		t.Errorf("bad reply: %+v", r)
+	}
+	if r.ICMPType == nil || *r.ICMPType != 0 ||
+		r.ICMPCode == nil || *r.ICMPCode != 0 || r.TCPFlags != nil {
+		t.Errorf("bad ICMP type/code: %+v", r)
+	}
+	if got := r.RTTDuration(); got != 12345*time.Microsecond {
+		t.Errorf("RTTDuration() = %v", got)
+	}
+	if got := r.RxTime(); !got.Equal(time.Unix(1700000000, 135845000)) {
+		t.Errorf("RxTime() = %v", got)
+	}
+
+	wantRTTs := []time.Duration{
+		12345 * time.Microsecond,
+		11 * time.Millisecond,
+		13 * time.Millisecond,
+	}
+	if got := res.RTTs(); !reflect.DeepEqual(got, wantRTTs) {
+		t.Errorf("RTTs() = %v, want %v", got, wantRTTs)
+	}
+	dups := res.Duplicates()
+	if len(dups) != 1 || dups[0].Seq != 1 || dups[0].RTT != 13 {
+		t.Errorf("Duplicates() = %+v", dups)
+	}
+
+	stats := res.Statistics
+	if stats == nil {
+		t.Fatal("no statistics")
+	}
+	want := PingStats{
+		Replies: 2, Loss: 1,
+		Min: 11, Max: 12.345, Avg: 11.673, StdDev: 0.673,
+	}
+	if *stats != want {
+		t.Errorf("Statistics = %+v, want %+v", *stats, want)
+	}
+	if stats.MinRTT() != 11*time.Millisecond ||
+		stats.MaxRTT() != 12345*time.Microsecond ||
+		stats.AvgRTT() != 11673*time.Microsecond ||
+		stats.StdDevRTT() != 673*time.Microsecond {
+		t.Errorf("bad RTT durations: %v %v %v %v", stats.MinRTT(),
+			stats.AvgRTT(), stats.MaxRTT(), stats.StdDevRTT())
+	}
+}
+
+// A method that PingMethod does not know about, and fields that
+// PingResult does not model, must not stop the result from decoding
+func TestPingResultUnknownMethod(t *testing.T) {
+	res := decodePingSample(t, "ping_tcp_syn_sport.json")
+
+	if res.Method != "tcp-syn-sport" {
+		t.Errorf("Method = %q", res.Method)
+	}
+	if !reflect.DeepEqual(res.Flags, []string{"tbt"}) {
+		t.Errorf("Flags = %v", res.Flags)
+	}
+	if !strings.Contains(string(res.Raw()), `"sport":40000`) {
+		t.Errorf("Raw() lost unmodeled fields: %s", res.Raw())
+	}
+	if len(res.Responses) != 1 {
+		t.Fatalf("got %d responses, want 1", len(res.Responses))
+	}
+	r := res.Responses[0]
+	if r.Proto != "tcp" || r.TCPFlags == nil || *r.TCPFlags != 18 ||
+		r.ICMPType != nil || !r.HasFlag("replyipid") || r.HasFlag("nope") {
+		t.Errorf("bad reply: %+v", r)
+	}
+	// no rx timestamp, so this is computed from tx + RTT
+	if got := r.RxTime(); !got.Equal(time.Unix(1700000100, 750000)) {
+		t.Errorf("RxTime() = %v", got)
+	}
+}
+
+func TestPingResultLoss(t *testing.T) {
+	res := decodePingSample(t, "ping_loss.json")
+
+	if res.Method != "udp-dport" || len(res.Responses) != 0 {
+		t.Errorf("bad result: %+v", res)
+	}
+	if len(res.RTTs()) != 0 || len(res.Duplicates()) != 0 {
+		t.Errorf("expected no RTTs or duplicates")
+	}
+	want := PingStats{Replies: 0, Loss: 4}
+	if res.Statistics == nil || *res.Statistics != want {
+		t.Errorf("Statistics = %+v, want %+v", res.Statistics, want)
+	}
+}
+
+func TestNewPingResultFromJson(t *testing.T) {
+	res, err := NewPingResultFromJson(readSample(t, "ping_icmp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.UserID != 7 || len(res.Responses) != 3 {
+		t.Errorf("bad result: %+v", res)
+	}
+	if _, err := NewPingResultFromJson(`{"type":"ping","ttl":"x"}`); err == nil {
+		t.Errorf("expected an error for a malformed result")
+	}
+}
@@ -0,0 +1,284 @@
+package measurement
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPingAsCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		ping Ping
+		want string
+	}{
+		{"zero", Ping{}, ""},
+		{"defaults", Ping{
+			ProbeCount: pingDefProbeCount,
+			Wait:       pingDefWait,
+			TTL:        pingDefTTL,
+			Timeout:    pingDefTimeout,
+			Method:     ICMP_ECHO,
+		}, ""},
+		{"tcp ack", Ping{TCPAck: 12345}, "-A 12345"},
+		{"payload", Ping{Payload: "deadbeef"}, "-B deadbeef"},
+		{"probe count", Ping{ProbeCount: 10}, "-c 10"},
+		{"icmp sum", Ping{ICMPSum: 4660}, "-C 4660"},
+		{"dst port", Ping{DstPort: 80}, "-d 80"},
+		{"src port", Ping{SrcPort: 5000}, "-F 5000"},
+		{"wait", Ping{Wait: 2}, "-i 2"},
+		{"ttl", Ping{TTL: 32}, "-m 32"},
+		{"mtu", Ping{MTU: 1280}, "-M 1280"},
+		{"reply count", Ping{ReplyCount: 2}, "-o 2"},
+		{"dl", Ping{DL: true}, "-O dl"},
+		{"dltx", Ping{DLTx: true}, "-O dltx"},
+		{"nosrc", Ping{NoSrc: true}, "-O nosrc"},
+		{"raw", Ping{Raw: true}, "-O raw"},
+		{"sockrx", Ping{SockRx: true}, "-O sockrx"},
+		{"spoof", Ping{Spoof: true, SrcAddr: "192.0.2.1"},
+			"-O spoof -S 192.0.2.1"},
+		{"tbt", Ping{TBT: true, MTU: 1280}, "-M 1280 -O tbt"},
+		{"pattern", Ping{Pattern: "ab"}, "-p ab"},
+		{"method", Ping{Method: TCP_SYN}, "-P tcp-syn"},
+		{"router", Ping{RouterAddr: "192.0.2.254"}, "-r 192.0.2.254"},
+		{"record route", Ping{RecordRoute: true}, "-R"},
+		{"size", Ping{Size: 1500}, "-s 1500"},
+		{"src addr", Ping{SrcAddr: "192.0.2.1"}, "-S 192.0.2.1"},
+		{"timestamp", Ping{Timestamp: "tsonly"}, "-T tsonly"},
+		{"timeout", Ping{Timeout: 5}, "-W 5"},
+		{"several -O", Ping{DL: true, NoSrc: true, Raw: true},
+			"-O dl -O nosrc -O raw"},
+		{"everything", Ping{
+			TCPAck:      1,
+			Payload:     "00ff",
+			ProbeCount:  3,
+			ICMPSum:     2,
+			DstPort:     33435,
+			SrcPort:     4,
+			Wait:        5,
+			TTL:         6,
+			MTU:         1400,
+			ReplyCount:  7,
+			NoSrc:       true,
+			Pattern:     "0a0b",
+			Method:      UDP_DPORT,
+			RouterAddr:  "192.0.2.254",
+			RecordRoute: true,
+			Size:        100,
+			SrcAddr:     "192.0.2.1",
+			Timestamp:   "tsandaddr",
+			Timeout:     8,
+		}, "-A 1 -B 00ff -c 3 -C 2 -d 33435 -F 4 -i 5 -m 6 -M 1400 -o 7 " +
+			"-O nosrc -p 0a0b -P udp-dport -r 192.0.2.254 -R -s 100 " +
+			"-S 192.0.2.1 -T tsandaddr -W 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ping.AsCommand(); got != tt.want {
+				t.Errorf("AsCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPingAsCommandHex(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"deadbeef", "deadbeef"},
+		{"DEADBEEF", "deadbeef"},
+		{"0xdeadbeef", "deadbeef"},
+		{"0XDeadBeef", "deadbeef"},
+		{"de:ad:be:ef", "deadbeef"},
+		{" de ad\tbe ef ", "deadbeef"},
+		{"0x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p := Ping{Payload: tt.in, Pattern: tt.in}
+			want := ""
+			if tt.want != "" {
+				want = "-B " + tt.want + " -p " + tt.want
+			}
+			if got := p.AsCommand(); got != want {
+				t.Errorf("AsCommand() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestPingAsCommandMethods(t *testing.T) {
+	for _, m := range PingMethodValues() {
+		t.Run(m.String(), func(t *testing.T) {
+			want := "-P " + m.String()
+			if m == ICMP_ECHO {
+				// scamper's default
+				want = ""
+			}
+			if got := (Ping{Method: m}).AsCommand(); got != want {
+				t.Errorf("AsCommand() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// Renders pings into commands, parses the arguments back out, and
+// checks that we get back what we put in
+func TestPingAsCommandRoundTrip(t *testing.T) {
+	pings := []Ping{
+		{Method: TCP_SYN, DstPort: 443, ProbeCount: 10},
+		{Method: UDP_DPORT, DstPort: 33435, Size: 128, Pattern: "0xCAFE"},
+		{Spoof: true, SrcAddr: "192.0.2.1", TTL: 16, Payload: "00:11"},
+	}
+	for _, p := range pings {
+		args := parseArgs(t, p.AsCommand())
+		got := Ping{
+			Method:     PingMethod(0),
+			ProbeCount: uint16(args.uint("c")),
+			DstPort:    uint16(args.uint("d")),
+			TTL:        uint8(args.uint("m")),
+			Size:       uint16(args.uint("s")),
+			Payload:    args.str("B"),
+			Pattern:    args.str("p"),
+			SrcAddr:    args.str("S"),
+			Spoof:      args.has("O", "spoof"),
+		}
+		if m := args.str("P"); m != "" {
+			method, err := PingMethodString(m)
+			if err != nil {
+				t.Fatalf("bad method %q: %v", m, err)
+			}
+			got.Method = method
+		}
+		want := p
+		want.Payload = normalizeHex(p.Payload)
+		want.Pattern = normalizeHex(p.Pattern)
+		if got != want {
+			t.Errorf("round trip of %q:\ngot  %+v\nwant %+v",
+				p.AsCommand(), got, want)
+		}
+	}
+}
+
+func TestPingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ping    Ping
+		wantErr string
+	}{
+		{"zero", Ping{}, ""},
+		{"spoof", Ping{Spoof: true, SrcAddr: "192.0.2.1"}, ""},
+		{"tbt", Ping{TBT: true, MTU: 1280}, ""},
+		{"bad method", Ping{Method: PingMethod(200)}, "invalid ping method"},
+		{"spoof without src", Ping{Spoof: true}, "spoof requires"},
+		{"tbt without mtu", Ping{TBT: true}, "tbt requires"},
+		{"raw and dltx", Ping{Raw: true, DLTx: true}, "mutually exclusive"},
+		{"dl and sockrx", Ping{DL: true, SockRx: true}, "mutually exclusive"},
+		{"odd payload", Ping{Payload: "abc"}, "even number"},
+		{"non-hex payload", Ping{Payload: "zz"}, "invalid hex"},
+		{"long pattern", Ping{Pattern: strings.Repeat("ab", 17)}, "at most 16"},
+		{"router with space", Ping{RouterAddr: "192.0.2.1 halt"}, "whitespace"},
+		{"router with newline", Ping{RouterAddr: "1.2.3.4\nhalt 1"}, "whitespace"},
+		{"src with newline", Ping{SrcAddr: "192.0.2.1\n"}, "whitespace"},
+		{"timestamp with space", Ping{Timestamp: "ts only"}, "whitespace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, tt.ping.Validate(), tt.wantErr)
+		})
+	}
+}
+
+func TestTaskValidate(t *testing.T) {
+	ping := func(target string, p Ping) Task {
+		task := Task{Type: TYPE_PING, Target: target}
+		task.Options.Ping = p
+		return task
+	}
+	tests := []struct {
+		name    string
+		task    Task
+		wantErr string
+	}{
+		{"ok", ping("8.8.8.8", Ping{Method: TCP_SYN}), ""},
+		{"no type", Task{Target: "8.8.8.8"}, "invalid task type"},
+		{"no target", ping("", Ping{}), "target must be set"},
+		{"bad options", ping("8.8.8.8", Ping{Spoof: true}), "spoof requires"},
+		{"target injection", ping("8.8.8.8\nhalt 1", Ping{}),
+			"control characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, tt.task.Validate(), tt.wantErr)
+		})
+	}
+}
+
+func TestTaskAsCommand(t *testing.T) {
+	task := Task{Type: TYPE_PING, Target: "8.8.8.8", UserId: 3}
+	task.Options.Ping = Ping{Method: TCP_SYN, DstPort: 443}
+	want := "ping -U 3 -d 443 -P tcp-syn 8.8.8.8"
+	if got := task.AsCommand(); got != want {
+		t.Errorf("AsCommand() = %q, want %q", got, want)
+	}
+}
+
+func checkErr(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want one containing %q", err, want)
+	}
+}
+
+// Arguments parsed from a rendered command, keyed by flag
+type cmdArgs map[string][]string
+
+func parseArgs(t *testing.T, cmd string) cmdArgs {
+	t.Helper()
+	args := cmdArgs{}
+	fields := strings.Fields(cmd)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if !strings.HasPrefix(f, "-") {
+			t.Fatalf("unexpected argument %q in %q", f, cmd)
+		}
+		f = f[1:]
+		if i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") {
+			args[f] = append(args[f], fields[i+1])
+			i++
+			continue
+		}
+		args[f] = append(args[f], "")
+	}
+	return args
+}
+
+func (a cmdArgs) str(f string) string {
+	if len(a[f]) == 0 {
+		return ""
+	}
+	return a[f][0]
+}
+
+func (a cmdArgs) uint(f string) uint64 {
+	var v uint64
+	for _, r := range a.str(f) {
+		v = v*10 + uint64(r-'0')
+	}
+	return v
+}
+
+func (a cmdArgs) has(f string, v string) bool {
+	for _, got := range a[f] {
+		if got == v {
+			return true
+		}
+	}
+	return false
+}
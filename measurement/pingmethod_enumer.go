@@ -1,6 +1,5 @@
 // Code generated by "enumer -type=PingMethod -json -text -linecomment"; DO NOT EDIT.
 
-//
 package measurement
 
 import (
@@ -2,32 +2,99 @@ package measurement
 
 import (
 	"encoding/json"
+	"math"
+	"time"
 )
 
-// TODO: this is ping only, do traceroute too
+// Interface implemented by all objects that scamper returns. Use a
+// type switch, or the Task accessors (e.g., Task.AsPing), to get at
+// the concrete result.
+type Result interface {
+	// Scamper's name for the object type (e.g., "ping", "cycle-start")
+	ResultType() string
+	// The user ID that the task was issued with (0 if none)
+	ResultUserId() uint64
+	// The original JSON received from scamper, including any
+	// fields that are not modeled by the concrete type
+	Raw() json.RawMessage
+
+	base() *ScResult
+}
+
+// Fields common to all scamper objects. Every concrete result type
+// embeds this. Objects of a type that scurry does not know about are
+// returned as a bare ScResult.
 type ScResult struct {
-	Type      string `json:"type"`
-	Version   string `json:"version"`
-	Method    string `json:"method"`
-	Src       string `json:"src"`
-	Dst       string `json:"dst"`
-	Start     ScTime `json:"start"`
-	PingSent  int    `json:"ping_sent"`
-	ProbeSize int    `json:"probe_size"`
-	UserID    uint64 `json:"userid"`
-	TTL       uint8  `json:"ttl"`
-	Wait      int    `json:"wait"`
-	Timeout   int    `json:"timeout"`
-	// TODO responses
-	// TODO statistics
-}
-
-func NewScResultFromJson(scJson string) (*ScResult, error) {
-	var res ScResult
-	if err := json.Unmarshal([]byte(scJson), &res); err != nil {
+	Type    string `json:"type"`
+	Version string `json:"version,omitempty"`
+	UserID  uint64 `json:"userid,omitempty"`
+
+	raw json.RawMessage // original JSON from scamper
+}
+
+// Constructors for each object type that scamper can return, keyed
+// by the object's "type" field.
+var resultTypes = map[string]func() Result{
+	"ping":        func() Result { return &PingResult{} },
+	"trace":       func() Result { return &TraceResult{} },
+	"tracelb":     func() Result { return &TracelbResult{} },
+	"dealias":     func() Result { return &DealiasResult{} },
+	"sting":       func() Result { return &StingResult{} },
+	"tbit":        func() Result { return &TbitResult{} },
+	"sniff":       func() Result { return &SniffResult{} },
+	"host":        func() Result { return &HostResult{} },
+	"http":        func() Result { return &HttpResult{} },
+	"cycle-start": func() Result { return &CycleResult{} },
+	"cycle-def":   func() Result { return &CycleResult{} },
+	"cycle-stop":  func() Result { return &CycleResult{} },
+	"list":        func() Result { return &ListResult{} },
+}
+
+// Parses a JSON object received from scamper into the concrete Result
+// type matching its "type" field.
+//
+// If the object has a valid header but its body cannot be decoded, the
+// bare *ScResult is returned along with the error so that the result
+// can still be matched to its task.
+func NewResultFromJson(scJson string) (Result, error) {
+	var hdr ScResult
+	if err := decodeResult(scJson, &hdr); err != nil {
 		return nil, err
 	}
-	return &res, nil
+	newRes, exists := resultTypes[hdr.Type]
+	if !exists {
+		// forward-compatibility: hand back what we know
+		return &hdr, nil
+	}
+	res := newRes()
+	if err := decodeResult(scJson, res); err != nil {
+		return &hdr, err
+	}
+	return res, nil
+}
+
+func decodeResult(scJson string, res Result) error {
+	if err := json.Unmarshal([]byte(scJson), res); err != nil {
+		return err
+	}
+	res.base().raw = json.RawMessage(scJson)
+	return nil
+}
+
+func (r *ScResult) base() *ScResult {
+	return r
+}
+
+func (r ScResult) ResultType() string {
+	return r.Type
+}
+
+func (r ScResult) ResultUserId() uint64 {
+	return r.UserID
+}
+
+func (r ScResult) Raw() json.RawMessage {
+	return r.raw
 }
 
 func (r ScResult) String() string {
@@ -39,3 +106,12 @@ type ScTime struct {
 	Sec  uint64 `json:"sec"`
 	Usec uint64 `json:"usec"`
 }
+
+func (t ScTime) Time() time.Time {
+	return time.Unix(int64(t.Sec), int64(t.Usec)*int64(time.Microsecond))
+}
+
+// Converts a scamper RTT (floating point milliseconds) to a Duration
+func msToDuration(ms float64) time.Duration {
+	return time.Duration(math.Round(ms * float64(time.Millisecond)))
+}
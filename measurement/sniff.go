@@ -0,0 +1,34 @@
+package measurement
+
+import (
+	"fmt"
+)
+
+// Represents a scamper "sniff" task
+//
+// The Task Target for a sniff task is the expression that selects
+// which packets to capture, e.g., "icmp[icmpid] == 1234".
+//
+// Implements ScCommand
+type Sniff struct {
+	LimitPktCount uint32 `short:"c" help:"Number of packets to capture before stopping."`
+	LimitTime     uint32 `short:"G" help:"Length of time, in seconds, to capture packets for."`
+	SrcAddr       string `short:"S" help:"Address of the interface to capture packets on (required)."`
+}
+
+func (s Sniff) AsCommand() string {
+	b := &cmdBuilder{}
+	b.uint("c", uint64(s.LimitPktCount), 0)
+	b.uint("G", uint64(s.LimitTime), 0)
+	b.str("S", s.SrcAddr)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (s Sniff) Validate() error {
+	if s.SrcAddr == "" {
+		return fmt.Errorf("sniff requires a source address")
+	}
+	return validateArg("source address", s.SrcAddr)
+}
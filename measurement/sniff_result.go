@@ -0,0 +1,30 @@
+package measurement
+
+import (
+	"encoding/json"
+)
+
+// Result of a scamper "sniff" task
+type SniffResult struct {
+	ScResult
+	Src           string     `json:"src"`
+	Start         ScTime     `json:"start"`
+	Finish        *ScTime    `json:"finish,omitempty"`
+	LimitPktCount int        `json:"limit_pkt_count,omitempty"`
+	LimitTime     int        `json:"limit_time,omitempty"`
+	ICMPId        uint16     `json:"icmpid,omitempty"`
+	Pkts          []ScPacket `json:"pkts,omitempty"`
+}
+
+func NewSniffResultFromJson(scJson string) (*SniffResult, error) {
+	var res SniffResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r SniffResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
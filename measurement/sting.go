@@ -0,0 +1,71 @@
+package measurement
+
+import (
+	"fmt"
+)
+
+// Represents a scamper "sting" (one-way TCP loss) task
+//
+// Implements ScCommand
+type Sting struct {
+	Count        uint16            `short:"c" default:"48" help:"Number of data segments to send in the data-seeding phase."`
+	DstPort      uint16            `short:"d" default:"80" help:"TCP destination port of the target."`
+	Distribution StingDistribution `short:"f" default:"uniform" help:"Distribution of inter-packet delays in the data-seeding phase."`
+	Request      uint8             `help:"ID of the default request to use (0 = HTTP GET /)."`
+	Hole         uint16            `short:"H" default:"3" help:"Size of the initial hole left in the data-seeding phase."`
+	Inter        uint32            `short:"i" default:"2000" help:"Length of time to wait, in milliseconds, between the data-seeding and hole-filling phases."`
+	Mean         uint32            `short:"m" default:"100" help:"Mean inter-packet delay, in milliseconds, in the data-seeding phase."`
+	SrcPort      uint16            `help:"TCP source port to use."`
+}
+
+//go:generate enumer -type=StingDistribution -json -text -linecomment
+type StingDistribution uint8
+
+const (
+	STING_DIST_UNIFORM  StingDistribution = iota // uniform
+	STING_DIST_EXPO                              // expo
+	STING_DIST_PERIODIC                          // periodic
+)
+
+// Scamper's defaults for the sting options that have them. Options
+// set to these values are omitted from the command.
+const (
+	stingDefCount   = 48
+	stingDefDstPort = 80
+	stingDefHole    = 3
+	stingDefInter   = 2000
+	stingDefMean    = 100
+)
+
+// Scamper identifies distributions by number
+func (d StingDistribution) scamperId() uint64 {
+	switch d {
+	case STING_DIST_EXPO:
+		return 1
+	case STING_DIST_PERIODIC:
+		return 2
+	}
+	return 3
+}
+
+func (s Sting) AsCommand() string {
+	b := &cmdBuilder{}
+	b.uint("c", uint64(s.Count), stingDefCount)
+	b.uint("d", uint64(s.DstPort), stingDefDstPort)
+	b.uint("f", s.Distribution.scamperId(), STING_DIST_UNIFORM.scamperId())
+	b.uint("h", uint64(s.Request), 0)
+	b.uint("H", uint64(s.Hole), stingDefHole)
+	b.uint("i", uint64(s.Inter), stingDefInter)
+	b.uint("m", uint64(s.Mean), stingDefMean)
+	b.uint("s", uint64(s.SrcPort), 0)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (s Sting) Validate() error {
+	if !s.Distribution.IsAStingDistribution() {
+		return fmt.Errorf("invalid sting distribution: %s", s.Distribution)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package measurement
+
+import (
+	"encoding/json"
+)
+
+// Result of a scamper "sting" task
+type StingResult struct {
+	ScResult
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+	SrcPort uint16 `json:"sport"`
+	DstPort uint16 `json:"dport"`
+	Start   ScTime `json:"start"`
+	Count   int    `json:"count"`
+	Mean    int    `json:"mean"`
+	Inter   int    `json:"inter"`
+	// Scamper's name for the inter-packet delay distribution
+	// (e.g., "uniform"), left as a string in case a newer scamper
+	// reports one that StingDistribution lacks. See
+	// DelayDistribution.
+	Distribution string     `json:"dist"`
+	SynRetx      int        `json:"synretx"`
+	DataRetx     int        `json:"dataretx"`
+	SeqSkip      int        `json:"seqskip"`
+	DataAckCount int        `json:"dataackc"`
+	HoleCount    int        `json:"holec"`
+	Pkts         []ScPacket `json:"pkts,omitempty"`
+}
+
+// A raw packet sent or received during a measurement
+type ScPacket struct {
+	Time  ScTime   `json:"tm"`
+	Dir   string   `json:"dir,omitempty"` // "tx" or "rx"
+	Len   int      `json:"len"`
+	Data  string   `json:"data,omitempty"` // hex
+	Flags []string `json:"flags,omitempty"`
+}
+
+func NewStingResultFromJson(scJson string) (*StingResult, error) {
+	var res StingResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r StingResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the delay distribution as a StingDistribution, or false if
+// it is one that scurry does not know about.
+func (r StingResult) DelayDistribution() (StingDistribution, bool) {
+	d, err := StingDistributionString(r.Distribution)
+	return d, err == nil
+}
+
+// Returns the fraction of data segments lost in the forward
+// direction (i.e., towards the target).
+func (r StingResult) ForwardLoss() float64 {
+	if r.Count == 0 {
+		return 0
+	}
+	return float64(r.HoleCount) / float64(r.Count)
+}
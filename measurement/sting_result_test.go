@@ -0,0 +1,28 @@
+package measurement
+
+import "testing"
+
+func TestStingResult(t *testing.T) {
+	for dist, want := range map[string]bool{
+		"expo": true, "uniform": true, "pareto": false,
+	} {
+		res, err := NewResultFromJson(`{"type":"sting", ` +
+			`"dst":"192.0.2.1", "count":48, "holec":12, ` +
+			`"dist":"` + dist + `"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sting, ok := res.(*StingResult)
+		if !ok {
+			t.Fatalf("decoded %s as %T, want *StingResult", dist, res)
+		}
+		d, ok := sting.DelayDistribution()
+		if sting.Distribution != dist || ok != want ||
+			(ok && d.String() != dist) {
+			t.Errorf("%s: DelayDistribution() = %v, %v", dist, d, ok)
+		}
+		if sting.ForwardLoss() != 0.25 {
+			t.Errorf("ForwardLoss() = %v", sting.ForwardLoss())
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Code generated by "enumer -type=StingDistribution -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _StingDistributionName = "uniformexpoperiodic"
+
+var _StingDistributionIndex = [...]uint8{0, 7, 11, 19}
+
+func (i StingDistribution) String() string {
+	if i >= StingDistribution(len(_StingDistributionIndex)-1) {
+		return fmt.Sprintf("StingDistribution(%d)", i)
+	}
+	return _StingDistributionName[_StingDistributionIndex[i]:_StingDistributionIndex[i+1]]
+}
+
+var _StingDistributionValues = []StingDistribution{0, 1, 2}
+
+var _StingDistributionNameToValueMap = map[string]StingDistribution{
+	_StingDistributionName[0:7]:   0,
+	_StingDistributionName[7:11]:  1,
+	_StingDistributionName[11:19]: 2,
+}
+
+// StingDistributionString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func StingDistributionString(s string) (StingDistribution, error) {
+	if val, ok := _StingDistributionNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to StingDistribution values", s)
+}
+
+// StingDistributionValues returns all values of the enum
+func StingDistributionValues() []StingDistribution {
+	return _StingDistributionValues
+}
+
+// IsAStingDistribution returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i StingDistribution) IsAStingDistribution() bool {
+	for _, v := range _StingDistributionValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for StingDistribution
+func (i StingDistribution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for StingDistribution
+func (i *StingDistribution) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("StingDistribution should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = StingDistributionString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for StingDistribution
+func (i StingDistribution) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for StingDistribution
+func (i *StingDistribution) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = StingDistributionString(string(text))
+	return err
+}
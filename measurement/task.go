@@ -3,6 +3,9 @@ package measurement
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+	"unicode"
 )
 
 // Central measurement task object. Represents both a measurment
@@ -19,19 +22,106 @@ type Task struct {
 	Target  string   `json:"target"`
 	Options TaskOpts `json:"options"`
 
-	Result *ScResult `json:"result"`
+	// Optional identifier chosen by the user, which is returned
+	// with the task. Controller.Cancel can use it to cancel tasks
+	// sent on the TaskQueue, so it should be unique among the
+	// outstanding tasks.
+	Id string `json:"id,omitempty"`
+
+	// Names of the vantage points to run the task on (when using
+	// a MultiController). If empty, the task is run on all of
+	// them.
+	Vantages []string `json:"vantages,omitempty"`
+	// Name of the vantage point that ran the task. Set by the
+	// MultiController.
+	Vantage string `json:"vantage,omitempty"`
+
+	// Optional limit on how long each attempt at the task may
+	// remain outstanding once it has been sent to scamper. If
+	// neither this nor Deadline is set, the Controller's default
+	// timeout (if any) is used.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Optional absolute deadline for the task, across all
+	// attempts. If Timeout is also set, whichever is sooner
+	// applies.
+	Deadline time.Time `json:"-"`
+
+	Result Result `json:"result"`
+	// Where the task is in its lifecycle. Set by the Controller.
+	Status TaskStatus `json:"status"`
+	// Set if the task failed (e.g., because it was rejected by
+	// scamper, or cancelled)
+	Error string `json:"error,omitempty"`
+	// Previous, failed, attempts at the task (if the Controller
+	// has been configured to retry tasks)
+	Attempts []TaskAttempt `json:"attempts,omitempty"`
 
 	UserId uint64 // used internally to match results with measurements
 }
 
+// The outcome of a failed attempt at executing a Task
+type TaskAttempt struct {
+	UserId uint64     `json:"userid"`
+	Status TaskStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+//go:generate enumer -type=TaskStatus -json -text -linecomment
+type TaskStatus uint8
+
+const (
+	// Queued, but not yet sent to scamper
+	TASK_PENDING TaskStatus = iota // pending
+	// Sent to scamper, awaiting acceptance
+	TASK_SENT // sent
+	// Accepted by scamper, awaiting a result
+	TASK_ACCEPTED // accepted
+	// Result received from scamper
+	TASK_COMPLETED // completed
+	// Not accepted by scamper (or by the Controller)
+	TASK_REJECTED // rejected
+	// Did not finish within its timeout
+	TASK_TIMED_OUT // timed-out
+	// Cancelled by the user
+	TASK_CANCELLED // cancelled
+	// Still outstanding when the Controller shut down
+	TASK_ABANDONED // abandoned
+)
+
+// Returns true if the task will not progress any further.
+func (s TaskStatus) Finished() bool {
+	return s >= TASK_COMPLETED
+}
+
+//go:generate enumer -type=JsonFormat -json -text -linecomment
+type JsonFormat uint8
+
+const (
+	// The task, with the result as parsed by scurry. Fields that
+	// scurry does not model are dropped.
+	JSON_TASK JsonFormat = iota // task
+	// The task, with the result being the untouched object received
+	// from scamper.
+	JSON_TASK_RAW // task-raw
+	// Only the untouched object received from scamper.
+	JSON_SCAMPER // scamper
+)
+
 type ScCommand interface {
 	AsCommand() string
 }
 
 type TaskOpts struct {
 	// Type-specific config:
-	Ping  Ping  `json:"ping"`
-	Trace Trace `json:"trace"`
+	Ping    Ping    `json:"ping"`
+	Trace   Trace   `json:"trace"`
+	Tracelb Tracelb `json:"tracelb"`
+	Dealias Dealias `json:"dealias"`
+	Sting   Sting   `json:"sting"`
+	Tbit    Tbit    `json:"tbit"`
+	Sniff   Sniff   `json:"sniff"`
+	Host    Host    `json:"host"`
+	Http    Http    `json:"http"`
 }
 
 func (t Task) TypeOptions() ScCommand {
@@ -40,23 +130,177 @@ func (t Task) TypeOptions() ScCommand {
 		return t.Options.Ping
 	case TYPE_TRACE:
 		return t.Options.Trace
+	case TYPE_TRACELB:
+		return t.Options.Tracelb
+	case TYPE_DEALIAS:
+		return t.Options.Dealias
+	case TYPE_STING:
+		return t.Options.Sting
+	case TYPE_TBIT:
+		return t.Options.Tbit
+	case TYPE_SNIFF:
+		return t.Options.Sniff
+	case TYPE_HOST:
+		return t.Options.Host
+	case TYPE_HTTP:
+		return t.Options.Http
 	}
 	return Noop{}
 }
 
+// Type-safe accessors for the task result. These return false if the
+// task has no result, or if the result is of a different type.
+func (t Task) AsPing() (*PingResult, bool) {
+	r, ok := t.Result.(*PingResult)
+	return r, ok
+}
+
+func (t Task) AsTrace() (*TraceResult, bool) {
+	r, ok := t.Result.(*TraceResult)
+	return r, ok
+}
+
+func (t Task) AsTracelb() (*TracelbResult, bool) {
+	r, ok := t.Result.(*TracelbResult)
+	return r, ok
+}
+
+func (t Task) AsDealias() (*DealiasResult, bool) {
+	r, ok := t.Result.(*DealiasResult)
+	return r, ok
+}
+
+func (t Task) AsSting() (*StingResult, bool) {
+	r, ok := t.Result.(*StingResult)
+	return r, ok
+}
+
+func (t Task) AsTbit() (*TbitResult, bool) {
+	r, ok := t.Result.(*TbitResult)
+	return r, ok
+}
+
+func (t Task) AsSniff() (*SniffResult, bool) {
+	r, ok := t.Result.(*SniffResult)
+	return r, ok
+}
+
+func (t Task) AsHost() (*HostResult, bool) {
+	r, ok := t.Result.(*HostResult)
+	return r, ok
+}
+
+func (t Task) AsHttp() (*HttpResult, bool) {
+	r, ok := t.Result.(*HttpResult)
+	return r, ok
+}
+
+// Returns true if the task finished without a usable result.
+func (t Task) Failed() bool {
+	return t.Status.Finished() &&
+		(t.Status != TASK_COMPLETED || t.Error != "")
+}
+
+// Implemented by the type-specific options that can check themselves
+type optionValidator interface {
+	Validate() error
+}
+
+// Checks that the task can be rendered into a valid scamper command.
+// The Controller rejects tasks that fail this check.
+func (t Task) Validate() error {
+	if t.Type == TYPE_UNKNOWN || !t.Type.IsAType() {
+		return fmt.Errorf("invalid task type: %s", t.Type)
+	}
+	if t.Target == "" {
+		return fmt.Errorf("task target must be set")
+	}
+	if v, ok := t.TypeOptions().(optionValidator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	// scamper reads commands a line at a time, so anything that
+	// would split the command would inject another
+	if strings.IndexFunc(t.AsCommand(), unicode.IsControl) >= 0 {
+		return fmt.Errorf("task must not contain control characters")
+	}
+	return nil
+}
+
 func (t Task) AsCommand() string {
+	opts := t.TypeOptions().AsCommand()
+	if opts != "" {
+		opts += " "
+	}
 	return fmt.Sprintf(
-		"%s -U %d %s %s",
+		"%s -U %d %s%s",
 		t.Type.String(),
 		t.UserId,
-		t.TypeOptions().AsCommand(),
+		opts,
 		t.Target,
 	)
 }
 
 func (t Task) AsJson() (string, error) {
-	d, err := json.Marshal(t)
-	return string(d), err
+	return t.AsJsonFormat(JSON_TASK)
+}
+
+// Renders the task as JSON in the given format. See JsonFormat for
+// details.
+func (t Task) AsJsonFormat(format JsonFormat) (string, error) {
+	switch format {
+	case JSON_TASK:
+		d, err := json.Marshal(t)
+		return string(d), err
+
+	case JSON_TASK_RAW:
+		// swap in the untouched scamper object for the result
+		type plainTask Task
+		d, err := json.Marshal(struct {
+			plainTask
+			Result json.RawMessage `json:"result"`
+		}{plainTask(t), t.rawResult()})
+		return string(d), err
+
+	case JSON_SCAMPER:
+		raw := t.rawResult()
+		if raw == nil {
+			return "", fmt.Errorf("task has no result")
+		}
+		return string(raw), nil
+	}
+	return "", fmt.Errorf("invalid JSON format: %s", format)
+}
+
+// Decodes a task rendered as JSON_TASK or JSON_TASK_RAW. The result
+// is decoded into its concrete type, as by NewResultFromJson.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plainTask Task
+	tmp := struct {
+		*plainTask
+		Result json.RawMessage `json:"result"`
+	}{plainTask: (*plainTask)(t)}
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	t.Result = nil
+	if len(tmp.Result) == 0 || string(tmp.Result) == "null" {
+		return nil
+	}
+	res, err := NewResultFromJson(string(tmp.Result))
+	t.Result = res
+	if err != nil {
+		return fmt.Errorf("failed to decode task result: %v", err)
+	}
+	return nil
+}
+
+func (t Task) rawResult() json.RawMessage {
+	if t.Result == nil {
+		return nil
+	}
+	return t.Result.Raw()
 }
 
 func (t Task) String() string {
@@ -0,0 +1,73 @@
+package measurement
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTaskJsonRoundTrip(t *testing.T) {
+	res, err := NewResultFromJson(readSample(t, "ping_icmp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := Task{
+		Type:   TYPE_PING,
+		Target: "8.8.8.8",
+		Status: TASK_COMPLETED,
+		UserId: 7,
+		Result: res,
+	}
+	task.Options.Ping.ProbeCount = 3
+
+	for _, format := range []JsonFormat{JSON_TASK, JSON_TASK_RAW} {
+		t.Run(format.String(), func(t *testing.T) {
+			j, err := task.AsJsonFormat(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Task
+			if err := json.Unmarshal([]byte(j), &got); err != nil {
+				t.Fatalf("failed to decode %s: %v", j, err)
+			}
+			ping, ok := got.AsPing()
+			if !ok {
+				t.Fatalf("decoded result as %T, want *PingResult", got.Result)
+			}
+			want, _ := task.AsPing()
+			if !reflect.DeepEqual(ping.Responses, want.Responses) ||
+				!reflect.DeepEqual(ping.Statistics, want.Statistics) ||
+				ping.Dst != want.Dst || ping.UserID != want.UserID {
+				t.Errorf("result = %+v, want %+v", ping, want)
+			}
+			if got.Type != task.Type || got.Target != task.Target ||
+				got.Status != task.Status || got.UserId != task.UserId ||
+				got.Options.Ping.ProbeCount != 3 {
+				t.Errorf("task = %+v, want %+v", got, task)
+			}
+		})
+	}
+}
+
+func TestTaskJsonNoResult(t *testing.T) {
+	j, err := Task{Type: TYPE_PING, Target: "192.0.2.1"}.AsJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Task{Result: &ScResult{}}
+	if err := json.Unmarshal([]byte(j), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Result != nil || got.Target != "192.0.2.1" {
+		t.Errorf("task = %+v", got)
+	}
+
+	// results of unknown types come back as a bare ScResult
+	j = `{"type":"ping","target":"192.0.2.1","result":{"type":"new"}}`
+	if err := json.Unmarshal([]byte(j), &got); err != nil {
+		t.Fatal(err)
+	}
+	if res, ok := got.Result.(*ScResult); !ok || res.Type != "new" {
+		t.Errorf("result = %#v", got.Result)
+	}
+}
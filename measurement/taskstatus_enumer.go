@@ -0,0 +1,85 @@
+// Code generated by "enumer -type=TaskStatus -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TaskStatusName = "pendingsentacceptedcompletedrejectedtimed-outcancelledabandoned"
+
+var _TaskStatusIndex = [...]uint8{0, 7, 11, 19, 28, 36, 45, 54, 63}
+
+func (i TaskStatus) String() string {
+	if i >= TaskStatus(len(_TaskStatusIndex)-1) {
+		return fmt.Sprintf("TaskStatus(%d)", i)
+	}
+	return _TaskStatusName[_TaskStatusIndex[i]:_TaskStatusIndex[i+1]]
+}
+
+var _TaskStatusValues = []TaskStatus{0, 1, 2, 3, 4, 5, 6, 7}
+
+var _TaskStatusNameToValueMap = map[string]TaskStatus{
+	_TaskStatusName[0:7]:   0,
+	_TaskStatusName[7:11]:  1,
+	_TaskStatusName[11:19]: 2,
+	_TaskStatusName[19:28]: 3,
+	_TaskStatusName[28:36]: 4,
+	_TaskStatusName[36:45]: 5,
+	_TaskStatusName[45:54]: 6,
+	_TaskStatusName[54:63]: 7,
+}
+
+// TaskStatusString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TaskStatusString(s string) (TaskStatus, error) {
+	if val, ok := _TaskStatusNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TaskStatus values", s)
+}
+
+// TaskStatusValues returns all values of the enum
+func TaskStatusValues() []TaskStatus {
+	return _TaskStatusValues
+}
+
+// IsATaskStatus returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TaskStatus) IsATaskStatus() bool {
+	for _, v := range _TaskStatusValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TaskStatus
+func (i TaskStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TaskStatus
+func (i *TaskStatus) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TaskStatus should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TaskStatusString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TaskStatus
+func (i TaskStatus) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TaskStatus
+func (i *TaskStatus) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TaskStatusString(string(text))
+	return err
+}
@@ -0,0 +1,121 @@
+package measurement
+
+import (
+	"fmt"
+)
+
+// Represents a scamper "tbit" (TCP behaviour inference) task
+//
+// Implements ScCommand
+type Tbit struct {
+	Type        TbitType     `default:"pmtud" help:"Type of TCP behaviour test to run."`
+	App         TbitApp      `short:"p" default:"http" help:"Application protocol to use."`
+	DstPort     uint16       `short:"d" help:"TCP destination port of the target (defaults to the application's port)."`
+	SrcPort     uint16       `help:"TCP source port to use."`
+	ASN         uint32       `short:"b" help:"ASN to use when establishing a BGP session (bgp app)."`
+	Cookie      string       `short:"f" help:"TCP fast open cookie to use, in hex."`
+	MSS         uint16       `short:"m" help:"Maximum segment size to advertise."`
+	MTU         uint16       `short:"M" help:"MTU to use in the pmtud test."`
+	Options     []TbitOption `short:"O" help:"Additional tbit options."`
+	PTBSrc      string       `short:"P" help:"Source address to use when sending packet too big messages (pmtud)."`
+	Attempts    uint8        `short:"q" help:"Number of attempts to make."`
+	SrcAddr     string       `short:"S" help:"Source address to use."`
+	TTL         uint8        `short:"T" help:"TTL value to use for outgoing packets."`
+	URL         string       `short:"u" help:"URL to request (http app)."`
+	WindowScale uint8        `short:"w" help:"Window scale option to advertise."`
+}
+
+//go:generate enumer -type=TbitType -json -text -linecomment
+type TbitType uint8
+
+const (
+	TBIT_PMTUD      TbitType = iota // pmtud
+	TBIT_ECN                        // ecn
+	TBIT_NULL                       // null
+	TBIT_SACK_RCVR                  // sack-rcvr
+	TBIT_ICW                        // icw
+	TBIT_BLIND_RST                  // blind-rst
+	TBIT_BLIND_SYN                  // blind-syn
+	TBIT_BLIND_DATA                 // blind-data
+	TBIT_BLIND_FIN                  // blind-fin
+)
+
+//go:generate enumer -type=TbitApp -json -text -linecomment
+type TbitApp uint8
+
+const (
+	TBIT_APP_HTTP TbitApp = iota // http
+	TBIT_APP_BGP                 // bgp
+)
+
+//go:generate enumer -type=TbitOption -json -text -linecomment
+type TbitOption uint8
+
+const (
+	TBIT_OPT_BLACKHOLE TbitOption = iota // blackhole
+	TBIT_OPT_TCPTS                       // tcpts
+	TBIT_OPT_SACK                        // sack
+	TBIT_OPT_IPTS_SYN                    // ipts-syn
+	TBIT_OPT_IPRR_SYN                    // iprr-syn
+	TBIT_OPT_IPQS_SYN                    // ipqs-syn
+)
+
+func (t Tbit) AsCommand() string {
+	b := &cmdBuilder{}
+	if t.Type.IsATbitType() {
+		b.str("t", t.Type.String())
+	}
+	if t.App != TBIT_APP_HTTP && t.App.IsATbitApp() {
+		b.str("p", t.App.String())
+	}
+	b.uint("b", uint64(t.ASN), 0)
+	b.uint("d", uint64(t.DstPort), 0)
+	b.hex("f", t.Cookie)
+	b.uint("m", uint64(t.MSS), 0)
+	b.uint("M", uint64(t.MTU), 0)
+	for _, o := range t.Options {
+		if o.IsATbitOption() {
+			b.str("O", o.String())
+		}
+	}
+	b.str("P", t.PTBSrc)
+	b.uint("q", uint64(t.Attempts), 0)
+	b.uint("s", uint64(t.SrcPort), 0)
+	b.str("S", t.SrcAddr)
+	b.uint("T", uint64(t.TTL), 0)
+	b.str("u", t.URL)
+	b.uint("w", uint64(t.WindowScale), 0)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (t Tbit) Validate() error {
+	if !t.Type.IsATbitType() {
+		return fmt.Errorf("invalid tbit type: %s", t.Type)
+	}
+	if !t.App.IsATbitApp() {
+		return fmt.Errorf("invalid tbit app: %s", t.App)
+	}
+	for _, o := range t.Options {
+		if !o.IsATbitOption() {
+			return fmt.Errorf("invalid tbit option: %s", o)
+		}
+	}
+	if t.URL != "" && t.App != TBIT_APP_HTTP {
+		return fmt.Errorf("tbit URL is only valid for the http app")
+	}
+	if t.ASN != 0 && t.App != TBIT_APP_BGP {
+		return fmt.Errorf("tbit ASN is only valid for the bgp app")
+	}
+	if err := validateHex("cookie", t.Cookie, 0); err != nil {
+		return err
+	}
+	if err := validateArg("PTB source address", t.PTBSrc); err != nil {
+		return err
+	}
+	if err := validateArg("source address", t.SrcAddr); err != nil {
+		return err
+	}
+	return validateArg("URL", t.URL)
+}
@@ -0,0 +1,58 @@
+package measurement
+
+import (
+	"encoding/json"
+)
+
+// Result of a scamper "tbit" task
+type TbitResult struct {
+	ScResult
+	// Scamper's names for the test type (e.g., "pmtud") and the
+	// application (e.g., "http") are kept as strings, as scamper
+	// reports types (such as "abc") that can't be requested, and
+	// may add more. See TestType and TbitAppRes.AppType.
+	TbitType string      `json:"tbit_type"`
+	Result   string      `json:"result"`
+	Src      string      `json:"src"`
+	Dst      string      `json:"dst"`
+	SrcPort  uint16      `json:"sport"`
+	DstPort  uint16      `json:"dport"`
+	Start    ScTime      `json:"start"`
+	App      *TbitAppRes `json:"app,omitempty"`
+	Pkts     []ScPacket  `json:"pkts,omitempty"`
+}
+
+// The application used by a tbit task
+type TbitAppRes struct {
+	Type string `json:"type"`
+	Host string `json:"host,omitempty"`
+	File string `json:"file,omitempty"`
+	ASN  uint32 `json:"asn,omitempty"`
+}
+
+func NewTbitResultFromJson(scJson string) (*TbitResult, error) {
+	var res TbitResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r TbitResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the test type as a TbitType, or false if it is one that
+// scurry can't request.
+func (r TbitResult) TestType() (TbitType, bool) {
+	t, err := TbitTypeString(r.TbitType)
+	return t, err == nil
+}
+
+// Returns the application as a TbitApp, or false if it is one that
+// scurry does not know about.
+func (a TbitAppRes) AppType() (TbitApp, bool) {
+	app, err := TbitAppString(a.Type)
+	return app, err == nil
+}
@@ -0,0 +1,49 @@
+package measurement
+
+import "testing"
+
+func TestTbitResult(t *testing.T) {
+	res, err := NewResultFromJson(`{"type":"tbit", "version":"0.1", ` +
+		`"tbit_type":"pmtud", "result":"pmtud-success", ` +
+		`"dst":"192.0.2.1", "dport":80, ` +
+		`"app":{"type":"http", "host":"example.com", "file":"/"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbit, ok := res.(*TbitResult)
+	if !ok {
+		t.Fatalf("decoded as %T, want *TbitResult", res)
+	}
+	if tt, ok := tbit.TestType(); !ok || tt != TBIT_PMTUD {
+		t.Errorf("TestType() = %v, %v", tt, ok)
+	}
+	if tbit.App == nil {
+		t.Fatal("no app")
+	}
+	if app, ok := tbit.App.AppType(); !ok || app != TBIT_APP_HTTP {
+		t.Errorf("AppType() = %v, %v", app, ok)
+	}
+}
+
+// scamper's own abc test type (which can't be requested) and
+// applications that TbitApp lacks must not stop the result decoding
+func TestTbitResultUnknownValues(t *testing.T) {
+	res, err := NewResultFromJson(`{"type":"tbit", "tbit_type":"abc", ` +
+		`"result":"none", "dst":"192.0.2.1", "app":{"type":"smtp"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbit, ok := res.(*TbitResult)
+	if !ok {
+		t.Fatalf("decoded as %T, want *TbitResult", res)
+	}
+	if tbit.TbitType != "abc" || tbit.App == nil || tbit.App.Type != "smtp" {
+		t.Errorf("bad result: %+v", tbit)
+	}
+	if _, ok := tbit.TestType(); ok {
+		t.Errorf("TestType() recognised abc")
+	}
+	if _, ok := tbit.App.AppType(); ok {
+		t.Errorf("AppType() recognised an unknown application")
+	}
+}
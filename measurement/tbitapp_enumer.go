@@ -0,0 +1,79 @@
+// Code generated by "enumer -type=TbitApp -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TbitAppName = "httpbgp"
+
+var _TbitAppIndex = [...]uint8{0, 4, 7}
+
+func (i TbitApp) String() string {
+	if i >= TbitApp(len(_TbitAppIndex)-1) {
+		return fmt.Sprintf("TbitApp(%d)", i)
+	}
+	return _TbitAppName[_TbitAppIndex[i]:_TbitAppIndex[i+1]]
+}
+
+var _TbitAppValues = []TbitApp{0, 1}
+
+var _TbitAppNameToValueMap = map[string]TbitApp{
+	_TbitAppName[0:4]: 0,
+	_TbitAppName[4:7]: 1,
+}
+
+// TbitAppString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TbitAppString(s string) (TbitApp, error) {
+	if val, ok := _TbitAppNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TbitApp values", s)
+}
+
+// TbitAppValues returns all values of the enum
+func TbitAppValues() []TbitApp {
+	return _TbitAppValues
+}
+
+// IsATbitApp returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TbitApp) IsATbitApp() bool {
+	for _, v := range _TbitAppValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TbitApp
+func (i TbitApp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TbitApp
+func (i *TbitApp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TbitApp should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TbitAppString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TbitApp
+func (i TbitApp) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TbitApp
+func (i *TbitApp) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TbitAppString(string(text))
+	return err
+}
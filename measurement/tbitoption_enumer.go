@@ -0,0 +1,83 @@
+// Code generated by "enumer -type=TbitOption -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TbitOptionName = "blackholetcptssackipts-syniprr-synipqs-syn"
+
+var _TbitOptionIndex = [...]uint8{0, 9, 14, 18, 26, 34, 42}
+
+func (i TbitOption) String() string {
+	if i >= TbitOption(len(_TbitOptionIndex)-1) {
+		return fmt.Sprintf("TbitOption(%d)", i)
+	}
+	return _TbitOptionName[_TbitOptionIndex[i]:_TbitOptionIndex[i+1]]
+}
+
+var _TbitOptionValues = []TbitOption{0, 1, 2, 3, 4, 5}
+
+var _TbitOptionNameToValueMap = map[string]TbitOption{
+	_TbitOptionName[0:9]:   0,
+	_TbitOptionName[9:14]:  1,
+	_TbitOptionName[14:18]: 2,
+	_TbitOptionName[18:26]: 3,
+	_TbitOptionName[26:34]: 4,
+	_TbitOptionName[34:42]: 5,
+}
+
+// TbitOptionString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TbitOptionString(s string) (TbitOption, error) {
+	if val, ok := _TbitOptionNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TbitOption values", s)
+}
+
+// TbitOptionValues returns all values of the enum
+func TbitOptionValues() []TbitOption {
+	return _TbitOptionValues
+}
+
+// IsATbitOption returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TbitOption) IsATbitOption() bool {
+	for _, v := range _TbitOptionValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TbitOption
+func (i TbitOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TbitOption
+func (i *TbitOption) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TbitOption should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TbitOptionString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TbitOption
+func (i TbitOption) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TbitOption
+func (i *TbitOption) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TbitOptionString(string(text))
+	return err
+}
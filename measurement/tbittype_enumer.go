@@ -0,0 +1,86 @@
+// Code generated by "enumer -type=TbitType -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TbitTypeName = "pmtudecnnullsack-rcvricwblind-rstblind-synblind-datablind-fin"
+
+var _TbitTypeIndex = [...]uint8{0, 5, 8, 12, 21, 24, 33, 42, 52, 61}
+
+func (i TbitType) String() string {
+	if i >= TbitType(len(_TbitTypeIndex)-1) {
+		return fmt.Sprintf("TbitType(%d)", i)
+	}
+	return _TbitTypeName[_TbitTypeIndex[i]:_TbitTypeIndex[i+1]]
+}
+
+var _TbitTypeValues = []TbitType{0, 1, 2, 3, 4, 5, 6, 7, 8}
+
+var _TbitTypeNameToValueMap = map[string]TbitType{
+	_TbitTypeName[0:5]:   0,
+	_TbitTypeName[5:8]:   1,
+	_TbitTypeName[8:12]:  2,
+	_TbitTypeName[12:21]: 3,
+	_TbitTypeName[21:24]: 4,
+	_TbitTypeName[24:33]: 5,
+	_TbitTypeName[33:42]: 6,
+	_TbitTypeName[42:52]: 7,
+	_TbitTypeName[52:61]: 8,
+}
+
+// TbitTypeString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TbitTypeString(s string) (TbitType, error) {
+	if val, ok := _TbitTypeNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TbitType values", s)
+}
+
+// TbitTypeValues returns all values of the enum
+func TbitTypeValues() []TbitType {
+	return _TbitTypeValues
+}
+
+// IsATbitType returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TbitType) IsATbitType() bool {
+	for _, v := range _TbitTypeValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TbitType
+func (i TbitType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TbitType
+func (i *TbitType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TbitType should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TbitTypeString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TbitType
+func (i TbitType) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TbitType
+func (i *TbitType) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TbitTypeString(string(text))
+	return err
+}
@@ -1,10 +1,129 @@
 package measurement
 
+import (
+	"fmt"
+)
+
+// Represents a scamper "trace" task
+//
+// Implements ScCommand
 type Trace struct {
-	// TODO
+	Confidence  uint8       `short:"c" help:"Confidence level to attain that all hops have replied at a given TTL before moving on (95 or 99)."`
+	DstPort     uint16      `short:"d" help:"Base destination port value to use for UDP-based and TCP-based traceroute methods."`
+	FirstHop    uint8       `short:"f" default:"1" help:"TTL value to begin probing with."`
+	GapLimit    uint8       `short:"g" default:"5" help:"Number of unresponsive hops permitted until a conclusion is made that the destination is unresponsive."`
+	GapAction   uint8       `short:"G" help:"What to do when the gap limit is reached. 1 halts probing, 2 sends last-ditch probes."`
+	Loops       uint8       `short:"l" default:"1" help:"Number of loops allowed before stopping (see --ignore-loops)."`
+	IgnoreLoops bool        `help:"Don't stop when a loop is found (i.e., -l 0). Overrides --loops."`
+	MaxTTL      uint8       `short:"m" default:"255" help:"Maximum TTL value to probe with before stopping."`
+	PMTUD       bool        `short:"M" help:"Do path MTU discovery for the path."`
+	Squeries    uint8       `short:"N" help:"Number of consecutive hops to probe before stopping to wait for a response."`
+	Offset      uint16      `short:"o" help:"Fragmentation offset to use in probes."`
+	Payload     string      `short:"p" help:"Payload, in hex, to include in each probe. The size of the payload determines the size of each probe."`
+	Method      TraceMethod `short:"P" default:"udp-paris" help:"Type of traceroute probes to send. The paris methods keep the flow identifier constant."`
+	Attempts    uint8       `short:"q" default:"2" help:"Number of attempts to make at each hop."`
+	AllAttempts bool        `short:"Q" help:"Send all allotted attempts at each hop, rather than stopping after the first reply."`
+	RouterAddr  string      `short:"r" help:"IP address of the router to use."`
+	SrcPort     uint16      `help:"Source port to use in probes (the ICMP ID for ICMP methods)."`
+	SrcAddr     string      `short:"S" help:"Source address to use in probes."`
+	TOS         uint8       `help:"Type-of-service bits to set in the IP header."`
+	TTLDst      bool        `short:"T" help:"Do not stop when a time-exceeded message is received from the destination."`
+	Wait        uint8       `short:"w" default:"5" help:"Length of time to wait, in seconds, for a response to a probe."`
+	WaitProbe   uint8       `short:"W" help:"Minimum length of time to wait, in 10ms units, between probes."`
+	StopSet     []string    `short:"z" help:"Addresses that make up the global stop set. Probing stops when one of these addresses responds."`
+	LocalStop   string      `short:"Z" help:"Name of the local stop set to use."`
 }
 
+//go:generate enumer -type=TraceMethod -json -text -linecomment
+type TraceMethod uint8
+
+const (
+	TRACE_UDP_PARIS  TraceMethod = iota // udp-paris
+	TRACE_UDP                           // udp
+	TRACE_ICMP                          // icmp
+	TRACE_ICMP_PARIS                    // icmp-paris
+	TRACE_TCP                           // tcp
+	TRACE_TCP_ACK                       // tcp-ack
+)
+
+// Scamper's defaults for the trace options that have them. Options
+// set to these values are omitted from the command.
+const (
+	traceDefConfidence = 95
+	traceDefFirstHop   = 1
+	traceDefGapLimit   = 5
+	traceDefGapAction  = 1
+	traceDefLoops      = 1
+	traceDefMaxTTL     = 255
+	traceDefAttempts   = 2
+	traceDefWait       = 5
+)
+
 func (t Trace) AsCommand() string {
-	// TODO
-	return ""
+	b := &cmdBuilder{}
+	b.flag("M", t.PMTUD)
+	b.flag("Q", t.AllAttempts)
+	b.flag("T", t.TTLDst)
+	b.uint("c", uint64(t.Confidence), traceDefConfidence)
+	b.uint("d", uint64(t.DstPort), 0)
+	b.uint("f", uint64(t.FirstHop), traceDefFirstHop)
+	b.uint("g", uint64(t.GapLimit), traceDefGapLimit)
+	b.uint("G", uint64(t.GapAction), traceDefGapAction)
+	if t.IgnoreLoops {
+		// zero is omitted by uint
+		b.str("l", "0")
+	} else {
+		b.uint("l", uint64(t.Loops), traceDefLoops)
+	}
+	b.uint("m", uint64(t.MaxTTL), traceDefMaxTTL)
+	b.uint("N", uint64(t.Squeries), 0)
+	b.uint("o", uint64(t.Offset), 0)
+	b.hex("p", t.Payload)
+	if t.Method != TRACE_UDP_PARIS && t.Method.IsATraceMethod() {
+		b.str("P", t.Method.String())
+	}
+	b.uint("q", uint64(t.Attempts), traceDefAttempts)
+	b.str("r", t.RouterAddr)
+	b.uint("s", uint64(t.SrcPort), 0)
+	b.str("S", t.SrcAddr)
+	b.uint("t", uint64(t.TOS), 0)
+	b.uint("w", uint64(t.Wait), traceDefWait)
+	b.uint("W", uint64(t.WaitProbe), 0)
+	for _, addr := range t.StopSet {
+		b.str("z", addr)
+	}
+	b.str("Z", t.LocalStop)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (t Trace) Validate() error {
+	if !t.Method.IsATraceMethod() {
+		return fmt.Errorf("invalid trace method: %s", t.Method)
+	}
+	if t.Confidence != 0 && t.Confidence != 95 && t.Confidence != 99 {
+		return fmt.Errorf("trace confidence must be 95 or 99")
+	}
+	if t.GapAction > 2 {
+		return fmt.Errorf("trace gap action must be 1 or 2")
+	}
+	if t.FirstHop != 0 && t.MaxTTL != 0 && t.FirstHop > t.MaxTTL {
+		return fmt.Errorf("trace first hop must not exceed max TTL")
+	}
+	if err := validateHex("payload", t.Payload, 0); err != nil {
+		return err
+	}
+	if err := validateArg("router address", t.RouterAddr); err != nil {
+		return err
+	}
+	if err := validateArg("source address", t.SrcAddr); err != nil {
+		return err
+	}
+	for _, addr := range t.StopSet {
+		if err := validateArg("stop set address", addr); err != nil {
+			return err
+		}
+	}
+	return validateArg("local stop set", t.LocalStop)
 }
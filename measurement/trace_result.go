@@ -0,0 +1,134 @@
+package measurement
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result of a scamper "trace" task
+type TraceResult struct {
+	ScResult
+	// Scamper's name for the probe method (e.g.,
+	// "icmp-echo-paris"). Note that these differ from the
+	// TraceMethod names used to request a method, and newer
+	// versions of scamper may add more.
+	Method     string          `json:"method"`
+	Src        string          `json:"src"`
+	Dst        string          `json:"dst"`
+	SrcPort    uint16          `json:"sport,omitempty"`
+	DstPort    uint16          `json:"dport,omitempty"`
+	StopReason TraceStopReason `json:"stop_reason"`
+	// Interpretation depends on StopReason. E.g., the ICMP
+	// unreachable code for TRACE_STOP_UNREACH, or the ICMP type for
+	// TRACE_STOP_ICMP.
+	StopData   uint8      `json:"stop_data"`
+	Start      ScTime     `json:"start"`
+	HopCount   int        `json:"hop_count"`
+	Attempts   int        `json:"attempts"`
+	HopLimit   int        `json:"hoplimit"`
+	FirstHop   int        `json:"firsthop"`
+	Wait       int        `json:"wait"`
+	WaitProbe  int        `json:"wait_probe"`
+	TOS        uint8      `json:"tos"`
+	ProbeSize  int        `json:"probe_size"`
+	ProbeCount int        `json:"probe_count"`
+	Hops       []TraceHop `json:"hops"`
+}
+
+// A single reply received in response to a trace probe
+type TraceHop struct {
+	Addr      string  `json:"addr"`
+	ProbeTTL  uint8   `json:"probe_ttl"`
+	Attempt   int     `json:"probe_id"` // which attempt at this TTL
+	ProbeSize int     `json:"probe_size"`
+	Tx        *ScTime `json:"tx,omitempty"`
+	RTT       float64 `json:"rtt"` // milliseconds
+	ReplyTTL  uint8   `json:"reply_ttl"`
+	ReplyTOS  uint8   `json:"reply_tos"`
+	ReplyIPID uint16  `json:"reply_ipid,omitempty"`
+	ReplySize int     `json:"reply_size"`
+	ICMPType  *uint8  `json:"icmp_type,omitempty"`
+	ICMPCode  *uint8  `json:"icmp_code,omitempty"`
+	QuotedTTL *uint8  `json:"icmp_q_ttl,omitempty"`
+	QuotedLen *int    `json:"icmp_q_ipl,omitempty"`
+	QuotedTOS *uint8  `json:"icmp_q_tos,omitempty"`
+	TCPFlags  *uint8  `json:"tcp_flags,omitempty"`
+}
+
+//go:generate enumer -type=TraceStopReason -json -text -linecomment
+type TraceStopReason uint8
+
+const (
+	TRACE_STOP_NONE      TraceStopReason = iota // NONE
+	TRACE_STOP_COMPLETED                        // COMPLETED
+	TRACE_STOP_UNREACH                          // UNREACH
+	TRACE_STOP_ICMP                             // ICMP
+	TRACE_STOP_LOOP                             // LOOP
+	TRACE_STOP_GAPLIMIT                         // GAPLIMIT
+	TRACE_STOP_ERROR                            // ERROR
+	TRACE_STOP_HOPLIMIT                         // HOPLIMIT
+	TRACE_STOP_GSS                              // GSS
+	TRACE_STOP_HALTED                           // HALTED
+)
+
+func NewTraceResultFromJson(scJson string) (*TraceResult, error) {
+	var res TraceResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r TraceResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the replies received for probes sent with the given TTL.
+func (r TraceResult) HopsAt(ttl uint8) []TraceHop {
+	hops := []TraceHop{}
+	for _, h := range r.Hops {
+		if h.ProbeTTL == ttl {
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+// Returns the address that responded at each TTL, starting from the
+// first hop probed. Unresponsive hops are represented by an empty
+// string. If more than one address responded at a TTL, the first
+// reply is used.
+func (r TraceResult) Path() []string {
+	first := r.FirstHop
+	if first < 1 {
+		first = 1
+	}
+	last := r.HopCount
+	for _, h := range r.Hops {
+		if int(h.ProbeTTL) > last {
+			last = int(h.ProbeTTL)
+		}
+	}
+	if last < first {
+		return []string{}
+	}
+	path := make([]string, last-first+1)
+	for _, h := range r.Hops {
+		idx := int(h.ProbeTTL) - first
+		if idx < 0 || path[idx] != "" {
+			continue
+		}
+		path[idx] = h.Addr
+	}
+	return path
+}
+
+// Returns true if the trace reached the destination.
+func (r TraceResult) Completed() bool {
+	return r.StopReason == TRACE_STOP_COMPLETED
+}
+
+func (h TraceHop) RTTDuration() time.Duration {
+	return msToDuration(h.RTT)
+}
@@ -0,0 +1,137 @@
+package measurement
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeTraceSample(t *testing.T, name string) *TraceResult {
+	t.Helper()
+	res, err := NewResultFromJson(readSample(t, name))
+	if err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+	trace, ok := res.(*TraceResult)
+	if !ok {
+		t.Fatalf("decoded %s as %T, want *TraceResult", name, res)
+	}
+	return trace
+}
+
+func TestTraceResultCompleted(t *testing.T) {
+	res := decodeTraceSample(t, "trace_icmp_paris.json")
+
+	if res.ResultType() != "trace" || res.ResultUserId() != 12 {
+		t.Errorf("bad header: type %q userid %d",
+			res.ResultType(), res.ResultUserId())
+	}
+	if res.Method != "icmp-echo-paris" || res.Src != "192.0.2.10" ||
+		res.Dst != "198.51.100.7" || res.HopCount != 4 ||
+		res.FirstHop != 1 || res.ProbeCount != 7 {
+		t.Errorf("bad fields: %+v", res)
+	}
+	if res.StopReason != TRACE_STOP_COMPLETED || !res.Completed() {
+		t.Errorf("StopReason = %s, Completed() = %v", res.StopReason,
+			res.Completed())
+	}
+	if got := res.Start.Time(); !got.Equal(time.Unix(1700000200, 5000000)) {
+		t.Errorf("Start = %v", got)
+	}
+	if !strings.Contains(string(res.Raw()), `"extra":"unmodeled"`) {
+		t.Errorf("Raw() lost unmodeled fields")
+	}
+
+	if len(res.Hops) != 5 {
+		t.Fatalf("got %d hops, want 5", len(res.Hops))
+	}
+	h := res.Hops[0]
+	if h.Addr != "192.0.2.1" || h.ProbeTTL != 1 || h.Attempt != 1 ||
+		h.ReplyTTL != 64 || h.ReplyIPID != 1111 || h.ReplySize != 56 {
+		t.Errorf("bad hop: %+v", h)
+	}
+	if h.ICMPType == nil || *h.ICMPType != 11 || h.QuotedTTL == nil ||
+		*h.QuotedTTL != 1 || h.QuotedLen == nil || *h.QuotedLen != 44 ||
+		h.TCPFlags != nil {
+		t.Errorf("bad ICMP fields: %+v", h)
+	}
+	if h.Tx == nil || !h.Tx.Time().Equal(time.Unix(1700000200, 5100000)) {
+		t.Errorf("Tx = %v", h.Tx)
+	}
+	if got := h.RTTDuration(); got != 512*time.Microsecond {
+		t.Errorf("RTTDuration() = %v", got)
+	}
+	if last := res.Hops[4]; last.ICMPType == nil || *last.ICMPType != 0 ||
+		last.QuotedTTL != nil {
+		t.Errorf("bad echo reply: %+v", last)
+	}
+
+	// both attempts at the first hop were answered, nothing
+	// answered at the second, and the third is load balanced
+	for ttl, want := range map[uint8][]string{
+		1: {"192.0.2.1", "192.0.2.1"},
+		2: {},
+		3: {"203.0.113.5", "203.0.113.9"},
+		4: {"198.51.100.7"},
+		5: {},
+	} {
+		addrs := []string{}
+		for _, h := range res.HopsAt(ttl) {
+			addrs = append(addrs, h.Addr)
+		}
+		if !reflect.DeepEqual(addrs, want) {
+			t.Errorf("HopsAt(%d) = %v, want %v", ttl, addrs, want)
+		}
+	}
+
+	want := []string{"192.0.2.1", "", "203.0.113.5", "198.51.100.7"}
+	if got := res.Path(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+}
+
+func TestTraceResultGapLimit(t *testing.T) {
+	res := decodeTraceSample(t, "trace_gaplimit.json")
+
+	if res.Method != "udp-paris" || res.SrcPort != 41000 ||
+		res.DstPort != 33435 {
+		t.Errorf("bad fields: %+v", res)
+	}
+	if res.StopReason != TRACE_STOP_GAPLIMIT || res.Completed() {
+		t.Errorf("StopReason = %s, Completed() = %v", res.StopReason,
+			res.Completed())
+	}
+	// the path starts at the first hop probed, and runs to the last
+	// hop probed, even though nothing answered after the fourth
+	want := []string{"203.0.113.5", "203.0.113.20", "", "", ""}
+	if got := res.Path(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+	if hops := res.HopsAt(2); len(hops) != 0 {
+		t.Errorf("HopsAt(2) = %+v, before the first hop", hops)
+	}
+}
+
+func TestTraceResultStopReasons(t *testing.T) {
+	for _, want := range TraceStopReasonValues() {
+		res, err := NewTraceResultFromJson(fmt.Sprintf(
+			`{"type":"trace", "dst":"198.51.100.7", `+
+				`"stop_reason":"%s", "stop_data":3, "hops":[]}`, want))
+		if err != nil {
+			t.Errorf("%s: %v", want, err)
+			continue
+		}
+		if res.StopReason != want || res.StopData != 3 {
+			t.Errorf("StopReason = %s (%d), want %s", res.StopReason,
+				res.StopData, want)
+		}
+		if res.Completed() != (want == TRACE_STOP_COMPLETED) {
+			t.Errorf("%s: Completed() = %v", want, res.Completed())
+		}
+		if len(res.Path()) != 0 {
+			t.Errorf("%s: Path() = %q, want none", want, res.Path())
+		}
+	}
+}
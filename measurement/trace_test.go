@@ -0,0 +1,38 @@
+package measurement
+
+import "testing"
+
+func TestTraceAsCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace Trace
+		want  string
+	}{
+		{"zero", Trace{}, ""},
+		{"defaults", Trace{
+			Confidence: traceDefConfidence,
+			FirstHop:   traceDefFirstHop,
+			GapLimit:   traceDefGapLimit,
+			GapAction:  traceDefGapAction,
+			Loops:      traceDefLoops,
+			MaxTTL:     traceDefMaxTTL,
+			Attempts:   traceDefAttempts,
+			Wait:       traceDefWait,
+			Method:     TRACE_UDP_PARIS,
+		}, ""},
+		{"loops", Trace{Loops: 3}, "-l 3"},
+		{"ignore loops", Trace{IgnoreLoops: true}, "-l 0"},
+		{"ignore loops overrides loops",
+			Trace{Loops: traceDefLoops, IgnoreLoops: true}, "-l 0"},
+		{"method", Trace{Method: TRACE_ICMP_PARIS}, "-P icmp-paris"},
+		{"stop set", Trace{StopSet: []string{"192.0.2.1", "192.0.2.2"}},
+			"-z 192.0.2.1 -z 192.0.2.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.trace.AsCommand(); got != tt.want {
+				t.Errorf("AsCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package measurement
+
+import (
+	"fmt"
+)
+
+// Represents a scamper "tracelb" (MDA load-balancer traceroute) task
+//
+// Implements ScCommand
+type Tracelb struct {
+	Confidence    uint8         `short:"c" help:"Confidence level to attain that all next-hops of a node have been found (95 or 99)."`
+	DstPort       uint16        `short:"d" help:"Base destination port value to use."`
+	FirstHop      uint8         `short:"f" default:"1" help:"TTL value to begin probing with."`
+	GapLimit      uint8         `short:"g" default:"3" help:"Number of consecutive unresponsive hops permitted before stopping."`
+	Method        TracelbMethod `short:"P" default:"udp-dport" help:"Type of probes to send. The method determines which header field is varied to find load-balanced paths."`
+	Attempts      uint8         `short:"q" default:"2" help:"Number of attempts to make with each probe before giving up."`
+	ProbeCountMax uint32        `short:"Q" default:"3000" help:"Maximum number of probes to send before stopping."`
+	RouterAddr    string        `short:"r" help:"IP address of the router to use."`
+	SrcPort       uint16        `help:"Source port to use in probes."`
+	TOS           uint8         `help:"Type-of-service bits to set in the IP header."`
+	WaitTimeout   uint8         `short:"w" default:"5" help:"Length of time to wait, in seconds, for a response to a probe."`
+	WaitProbe     uint8         `short:"W" default:"25" help:"Minimum length of time to wait, in 10ms units, between probes."`
+	PTR           bool          `help:"Look up the PTR records of the addresses discovered (-O ptr)."`
+}
+
+//go:generate enumer -type=TracelbMethod -json -text -linecomment
+type TracelbMethod uint8
+
+const (
+	TRACELB_UDP_DPORT     TracelbMethod = iota // udp-dport
+	TRACELB_ICMP_ECHO                          // icmp-echo
+	TRACELB_UDP_SPORT                          // udp-sport
+	TRACELB_TCP_SPORT                          // tcp-sport
+	TRACELB_TCP_ACK_SPORT                      // tcp-ack-sport
+)
+
+// Scamper's defaults for the tracelb options that have them. Options
+// set to these values are omitted from the command.
+const (
+	tracelbDefConfidence    = 95
+	tracelbDefFirstHop      = 1
+	tracelbDefGapLimit      = 3
+	tracelbDefAttempts      = 2
+	tracelbDefProbeCountMax = 3000
+	tracelbDefWaitTimeout   = 5
+	tracelbDefWaitProbe     = 25
+)
+
+func (t Tracelb) AsCommand() string {
+	b := &cmdBuilder{}
+	b.uint("c", uint64(t.Confidence), tracelbDefConfidence)
+	b.uint("d", uint64(t.DstPort), 0)
+	b.uint("f", uint64(t.FirstHop), tracelbDefFirstHop)
+	b.uint("g", uint64(t.GapLimit), tracelbDefGapLimit)
+	if t.PTR {
+		b.str("O", "ptr")
+	}
+	if t.Method != TRACELB_UDP_DPORT && t.Method.IsATracelbMethod() {
+		b.str("P", t.Method.String())
+	}
+	b.uint("q", uint64(t.Attempts), tracelbDefAttempts)
+	b.uint("Q", uint64(t.ProbeCountMax), tracelbDefProbeCountMax)
+	b.str("r", t.RouterAddr)
+	b.uint("s", uint64(t.SrcPort), 0)
+	b.uint("t", uint64(t.TOS), 0)
+	b.uint("w", uint64(t.WaitTimeout), tracelbDefWaitTimeout)
+	b.uint("W", uint64(t.WaitProbe), tracelbDefWaitProbe)
+	return b.String()
+}
+
+// Checks that the options can be rendered into a valid scamper
+// command.
+func (t Tracelb) Validate() error {
+	if !t.Method.IsATracelbMethod() {
+		return fmt.Errorf("invalid tracelb method: %s", t.Method)
+	}
+	if t.Confidence != 0 && t.Confidence != 95 && t.Confidence != 99 {
+		return fmt.Errorf("tracelb confidence must be 95 or 99")
+	}
+	return validateArg("router address", t.RouterAddr)
+}
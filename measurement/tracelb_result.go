@@ -0,0 +1,121 @@
+package measurement
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Result of a scamper "tracelb" task
+type TracelbResult struct {
+	ScResult
+	// Scamper's name for the probe method (e.g., "udp-dport"). A
+	// plain string, so that a method this version of scurry doesn't
+	// know about can't stop the result decoding; see ProbeMethod.
+	Method        string        `json:"method"`
+	Src           string        `json:"src"`
+	Dst           string        `json:"dst"`
+	Start         ScTime        `json:"start"`
+	ProbeSize     int           `json:"probe_size"`
+	FirstHop      int           `json:"firsthop"`
+	Attempts      int           `json:"attempts"`
+	Confidence    int           `json:"confidence"`
+	TOS           uint8         `json:"tos"`
+	GapLimit      int           `json:"gaplimit"`
+	WaitTimeout   int           `json:"wait_timeout"`
+	WaitProbe     int           `json:"wait_probe"`
+	ProbeCount    int           `json:"probec"`
+	ProbeCountMax int           `json:"probec_max"`
+	NodeCount     int           `json:"nodec"`
+	LinkCount     int           `json:"linkc"`
+	Nodes         []TracelbNode `json:"nodes"`
+}
+
+// An interface discovered by a tracelb task, along with the links to
+// its next-hops.
+type TracelbNode struct {
+	Addr      string `json:"addr"`
+	Name      string `json:"name,omitempty"`
+	QuotedTTL *uint8 `json:"q_ttl,omitempty"`
+	LinkCount int    `json:"linkc"`
+	// Each link is a sequence of probe sets, one per hop between
+	// this node and the next. The final probe set in a link
+	// identifies the node at the far end.
+	Links [][]TracelbProbeSet `json:"links,omitempty"`
+}
+
+// The probes sent at a single hop of a link
+type TracelbProbeSet struct {
+	Addr   string         `json:"addr,omitempty"`
+	Probes []TracelbProbe `json:"probes"`
+}
+
+type TracelbProbe struct {
+	Tx         ScTime         `json:"tx"`
+	ReplyCount int            `json:"replyc"`
+	TTL        uint8          `json:"ttl"`
+	Attempt    int            `json:"attempt"`
+	FlowId     uint16         `json:"flowid"`
+	Replies    []TracelbReply `json:"replies,omitempty"`
+}
+
+type TracelbReply struct {
+	Rx        ScTime  `json:"rx"`
+	TTL       uint8   `json:"ttl"`
+	RTT       float64 `json:"rtt"` // milliseconds
+	ICMPType  *uint8  `json:"icmp_type,omitempty"`
+	ICMPCode  *uint8  `json:"icmp_code,omitempty"`
+	QuotedTOS *uint8  `json:"icmp_q_tos,omitempty"`
+	QuotedTTL *uint8  `json:"icmp_q_ttl,omitempty"`
+	TCPFlags  *uint8  `json:"tcp_flags,omitempty"`
+}
+
+// A directed link between two interfaces discovered by tracelb
+type TracelbLink struct {
+	From string
+	To   string
+	// Number of unresponsive hops between From and To
+	Gap int
+}
+
+func NewTracelbResultFromJson(scJson string) (*TracelbResult, error) {
+	var res TracelbResult
+	if err := decodeResult(scJson, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r TracelbResult) String() string {
+	d, _ := json.Marshal(r)
+	return string(d)
+}
+
+// Returns the probe method as a TracelbMethod, or false if it is one
+// that scurry does not know about.
+func (r TracelbResult) ProbeMethod() (TracelbMethod, bool) {
+	m, err := TracelbMethodString(r.Method)
+	return m, err == nil
+}
+
+// Returns all links between nodes. Load-balanced "diamonds" show up
+// as nodes with more than one outgoing link.
+func (r TracelbResult) Links() []TracelbLink {
+	links := []TracelbLink{}
+	for _, n := range r.Nodes {
+		for _, l := range n.Links {
+			if len(l) == 0 {
+				continue
+			}
+			links = append(links, TracelbLink{
+				From: n.Addr,
+				To:   l[len(l)-1].Addr,
+				Gap:  len(l) - 1,
+			})
+		}
+	}
+	return links
+}
+
+func (r TracelbReply) RTTDuration() time.Duration {
+	return msToDuration(r.RTT)
+}
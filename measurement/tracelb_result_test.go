@@ -0,0 +1,54 @@
+package measurement
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTracelbResult(t *testing.T) {
+	res, err := NewResultFromJson(`{"type":"tracelb", "version":"0.1", ` +
+		`"userid":3, "method":"icmp-echo", "src":"192.0.2.10", ` +
+		`"dst":"198.51.100.1", "nodec":3, "linkc":2, "nodes":[` +
+		`{"addr":"192.0.2.1", "linkc":1, "links":[[` +
+		`{"addr":"192.0.2.2", "probes":[]}]]}, ` +
+		`{"addr":"192.0.2.2", "linkc":1, "links":[[` +
+		`{"probes":[]}, {"addr":"198.51.100.1", "probes":[]}]]}, ` +
+		`{"addr":"198.51.100.1", "linkc":0}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb, ok := res.(*TracelbResult)
+	if !ok {
+		t.Fatalf("decoded as %T, want *TracelbResult", res)
+	}
+	if m, ok := lb.ProbeMethod(); !ok || m != TRACELB_ICMP_ECHO {
+		t.Errorf("ProbeMethod() = %v, %v", m, ok)
+	}
+	want := []TracelbLink{
+		{From: "192.0.2.1", To: "192.0.2.2"},
+		{From: "192.0.2.2", To: "198.51.100.1", Gap: 1},
+	}
+	if got := lb.Links(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Links() = %+v, want %+v", got, want)
+	}
+}
+
+// A method that TracelbMethod does not know about must not stop the
+// result from decoding
+func TestTracelbResultUnknownMethod(t *testing.T) {
+	res, err := NewResultFromJson(`{"type":"tracelb", ` +
+		`"method":"udp-sport-paris", "dst":"198.51.100.1", "nodes":[]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb, ok := res.(*TracelbResult)
+	if !ok {
+		t.Fatalf("decoded as %T, want *TracelbResult", res)
+	}
+	if lb.Method != "udp-sport-paris" || lb.Dst != "198.51.100.1" {
+		t.Errorf("bad result: %+v", lb)
+	}
+	if _, ok := lb.ProbeMethod(); ok {
+		t.Errorf("ProbeMethod() recognised an unknown method")
+	}
+}
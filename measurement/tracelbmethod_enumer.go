@@ -0,0 +1,82 @@
+// Code generated by "enumer -type=TracelbMethod -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TracelbMethodName = "udp-dporticmp-echoudp-sporttcp-sporttcp-ack-sport"
+
+var _TracelbMethodIndex = [...]uint8{0, 9, 18, 27, 36, 49}
+
+func (i TracelbMethod) String() string {
+	if i >= TracelbMethod(len(_TracelbMethodIndex)-1) {
+		return fmt.Sprintf("TracelbMethod(%d)", i)
+	}
+	return _TracelbMethodName[_TracelbMethodIndex[i]:_TracelbMethodIndex[i+1]]
+}
+
+var _TracelbMethodValues = []TracelbMethod{0, 1, 2, 3, 4}
+
+var _TracelbMethodNameToValueMap = map[string]TracelbMethod{
+	_TracelbMethodName[0:9]:   0,
+	_TracelbMethodName[9:18]:  1,
+	_TracelbMethodName[18:27]: 2,
+	_TracelbMethodName[27:36]: 3,
+	_TracelbMethodName[36:49]: 4,
+}
+
+// TracelbMethodString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TracelbMethodString(s string) (TracelbMethod, error) {
+	if val, ok := _TracelbMethodNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TracelbMethod values", s)
+}
+
+// TracelbMethodValues returns all values of the enum
+func TracelbMethodValues() []TracelbMethod {
+	return _TracelbMethodValues
+}
+
+// IsATracelbMethod returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TracelbMethod) IsATracelbMethod() bool {
+	for _, v := range _TracelbMethodValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TracelbMethod
+func (i TracelbMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TracelbMethod
+func (i *TracelbMethod) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TracelbMethod should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TracelbMethodString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TracelbMethod
+func (i TracelbMethod) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TracelbMethod
+func (i *TracelbMethod) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TracelbMethodString(string(text))
+	return err
+}
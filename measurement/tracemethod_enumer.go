@@ -0,0 +1,83 @@
+// Code generated by "enumer -type=TraceMethod -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TraceMethodName = "udp-parisudpicmpicmp-paristcptcp-ack"
+
+var _TraceMethodIndex = [...]uint8{0, 9, 12, 16, 26, 29, 36}
+
+func (i TraceMethod) String() string {
+	if i >= TraceMethod(len(_TraceMethodIndex)-1) {
+		return fmt.Sprintf("TraceMethod(%d)", i)
+	}
+	return _TraceMethodName[_TraceMethodIndex[i]:_TraceMethodIndex[i+1]]
+}
+
+var _TraceMethodValues = []TraceMethod{0, 1, 2, 3, 4, 5}
+
+var _TraceMethodNameToValueMap = map[string]TraceMethod{
+	_TraceMethodName[0:9]:   0,
+	_TraceMethodName[9:12]:  1,
+	_TraceMethodName[12:16]: 2,
+	_TraceMethodName[16:26]: 3,
+	_TraceMethodName[26:29]: 4,
+	_TraceMethodName[29:36]: 5,
+}
+
+// TraceMethodString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TraceMethodString(s string) (TraceMethod, error) {
+	if val, ok := _TraceMethodNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TraceMethod values", s)
+}
+
+// TraceMethodValues returns all values of the enum
+func TraceMethodValues() []TraceMethod {
+	return _TraceMethodValues
+}
+
+// IsATraceMethod returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TraceMethod) IsATraceMethod() bool {
+	for _, v := range _TraceMethodValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TraceMethod
+func (i TraceMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TraceMethod
+func (i *TraceMethod) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TraceMethod should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TraceMethodString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TraceMethod
+func (i TraceMethod) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TraceMethod
+func (i *TraceMethod) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TraceMethodString(string(text))
+	return err
+}
@@ -0,0 +1,87 @@
+// Code generated by "enumer -type=TraceStopReason -json -text -linecomment"; DO NOT EDIT.
+
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _TraceStopReasonName = "NONECOMPLETEDUNREACHICMPLOOPGAPLIMITERRORHOPLIMITGSSHALTED"
+
+var _TraceStopReasonIndex = [...]uint8{0, 4, 13, 20, 24, 28, 36, 41, 49, 52, 58}
+
+func (i TraceStopReason) String() string {
+	if i >= TraceStopReason(len(_TraceStopReasonIndex)-1) {
+		return fmt.Sprintf("TraceStopReason(%d)", i)
+	}
+	return _TraceStopReasonName[_TraceStopReasonIndex[i]:_TraceStopReasonIndex[i+1]]
+}
+
+var _TraceStopReasonValues = []TraceStopReason{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+var _TraceStopReasonNameToValueMap = map[string]TraceStopReason{
+	_TraceStopReasonName[0:4]:   0,
+	_TraceStopReasonName[4:13]:  1,
+	_TraceStopReasonName[13:20]: 2,
+	_TraceStopReasonName[20:24]: 3,
+	_TraceStopReasonName[24:28]: 4,
+	_TraceStopReasonName[28:36]: 5,
+	_TraceStopReasonName[36:41]: 6,
+	_TraceStopReasonName[41:49]: 7,
+	_TraceStopReasonName[49:52]: 8,
+	_TraceStopReasonName[52:58]: 9,
+}
+
+// TraceStopReasonString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func TraceStopReasonString(s string) (TraceStopReason, error) {
+	if val, ok := _TraceStopReasonNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to TraceStopReason values", s)
+}
+
+// TraceStopReasonValues returns all values of the enum
+func TraceStopReasonValues() []TraceStopReason {
+	return _TraceStopReasonValues
+}
+
+// IsATraceStopReason returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i TraceStopReason) IsATraceStopReason() bool {
+	for _, v := range _TraceStopReasonValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for TraceStopReason
+func (i TraceStopReason) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TraceStopReason
+func (i *TraceStopReason) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("TraceStopReason should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = TraceStopReasonString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for TraceStopReason
+func (i TraceStopReason) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for TraceStopReason
+func (i *TraceStopReason) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = TraceStopReasonString(string(text))
+	return err
+}
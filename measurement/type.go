@@ -7,4 +7,11 @@ const (
 	TYPE_UNKNOWN Type = iota // unknown
 	TYPE_PING                // ping
 	TYPE_TRACE               // trace
+	TYPE_TRACELB             // tracelb
+	TYPE_DEALIAS             // dealias
+	TYPE_STING               // sting
+	TYPE_TBIT                // tbit
+	TYPE_SNIFF               // sniff
+	TYPE_HOST                // host
+	TYPE_HTTP                // http
 )
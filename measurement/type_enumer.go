@@ -1,6 +1,5 @@
 // Code generated by "enumer -type=Type -json -text -linecomment"; DO NOT EDIT.
 
-//
 package measurement
 
 import (
@@ -8,9 +7,9 @@ import (
 	"fmt"
 )
 
-const _TypeName = "unknownpingtrace"
+const _TypeName = "unknownpingtracetracelbdealiasstingtbitsniffhosthttp"
 
-var _TypeIndex = [...]uint8{0, 7, 11, 16}
+var _TypeIndex = [...]uint8{0, 7, 11, 16, 23, 30, 35, 39, 44, 48, 52}
 
 func (i Type) String() string {
 	if i < 0 || i >= Type(len(_TypeIndex)-1) {
@@ -19,12 +18,19 @@ func (i Type) String() string {
 	return _TypeName[_TypeIndex[i]:_TypeIndex[i+1]]
 }
 
-var _TypeValues = []Type{0, 1, 2}
+var _TypeValues = []Type{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
 
 var _TypeNameToValueMap = map[string]Type{
 	_TypeName[0:7]:   0,
 	_TypeName[7:11]:  1,
 	_TypeName[11:16]: 2,
+	_TypeName[16:23]: 3,
+	_TypeName[23:30]: 4,
+	_TypeName[30:35]: 5,
+	_TypeName[35:39]: 6,
+	_TypeName[39:44]: 7,
+	_TypeName[44:48]: 8,
+	_TypeName[48:52]: 9,
 }
 
 // TypeString retrieves an enum value from the enum constants string name.
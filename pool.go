@@ -0,0 +1,238 @@
+package scurry
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/alistairking/scurry/measurement"
+	"github.com/rs/zerolog"
+)
+
+// How a Pool chooses which Controller to hand each task to
+//
+//go:generate enumer -type=PoolStrategy -json -text -linecomment
+type PoolStrategy uint8
+
+const (
+	// Each Controller in turn
+	POOL_ROUND_ROBIN PoolStrategy = iota // round-robin
+	// The Controller with the fewest outstanding (and queued)
+	// tasks
+	POOL_LEAST_OUTSTANDING // least-outstanding
+	// The Controller chosen by hashing the task's target, so that
+	// all tasks towards a target are run by the same scamper
+	// (which, combined with ControllerConfig.MaxPerTarget, avoids
+	// scamper serializing them)
+	POOL_TARGET_HASH // target-hash
+)
+
+type PoolConfig struct {
+	// One Controller is created for each config. Each may connect
+	// to an existing scamper, or run its own.
+	Controllers []ControllerConfig
+	Strategy    PoolStrategy
+
+	// Number of tasks that may be queued on the TaskQueue
+	// (default SEND_Q_LEN)
+	TaskQueueLen int
+	// Number of finished tasks that may be queued on the
+	// ResultQueue (default RECV_Q_LEN)
+	ResultQueueLen int
+}
+
+// Checks that the config is usable
+func (cfg PoolConfig) Validate() error {
+	if len(cfg.Controllers) == 0 {
+		return fmt.Errorf("pool must have at least one controller")
+	}
+	if !cfg.Strategy.IsAPoolStrategy() {
+		return fmt.Errorf("invalid pool strategy: %s", cfg.Strategy)
+	}
+	if cfg.TaskQueueLen < 0 {
+		return fmt.Errorf("task queue length must not be negative")
+	}
+	if cfg.ResultQueueLen < 0 {
+		return fmt.Errorf("result queue length must not be negative")
+	}
+	for i, ctrlCfg := range cfg.Controllers {
+		if err := ctrlCfg.Validate(); err != nil {
+			return fmt.Errorf("controller %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func (cfg PoolConfig) withDefaults() PoolConfig {
+	if cfg.TaskQueueLen == 0 {
+		cfg.TaskQueueLen = SEND_Q_LEN
+	}
+	if cfg.ResultQueueLen == 0 {
+		cfg.ResultQueueLen = RECV_Q_LEN
+	}
+	return cfg
+}
+
+// Load-balances tasks across several Controllers (and so several
+// scamper instances), and merges their results onto a single
+// ResultQueue. Each Controller assigns UserIds independently, so
+// UserIds are only unique per Controller.
+type Pool struct {
+	log    Logger
+	cfg    PoolConfig
+	ctrls  []*Controller
+	router *ctrlRouter
+
+	mu   *sync.Mutex
+	next int // for round-robin
+}
+
+func NewPool(log zerolog.Logger, cfg PoolConfig) (*Pool, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	p := &Pool{
+		log: initLogger(log, "pool"),
+		cfg: cfg,
+		mu:  &sync.Mutex{},
+	}
+
+	for i, ctrlCfg := range cfg.Controllers {
+		ctrl, err := NewController(log, ctrlCfg)
+		if err != nil {
+			for _, c := range p.ctrls {
+				c.Drain(context.Background())
+				c.Close()
+			}
+			return nil, fmt.Errorf("controller %d: %v", i, err)
+		}
+		p.ctrls = append(p.ctrls, ctrl)
+	}
+
+	// merge the results from each controller, and start handing
+	// out tasks
+	p.router = newCtrlRouter(p.log, p.ctrls, cfg.TaskQueueLen,
+		cfg.ResultQueueLen)
+	p.router.start(func(task measurement.Task) {
+		p.router.handoff(p.pick(task), task)
+	})
+
+	p.log.Debug().
+		Int("controllers", len(p.ctrls)).
+		Str("strategy", cfg.Strategy.String()).
+		Msgf("Pool online")
+
+	return p, nil
+}
+
+func (p *Pool) TaskQueue() chan measurement.Task {
+	return p.router.taskQ
+}
+
+func (p *Pool) ResultQueue() chan measurement.Task {
+	return p.router.resQ
+}
+
+// The Controllers in the pool
+func (p *Pool) Controllers() []*Controller {
+	return p.ctrls
+}
+
+// Submits a single task to one of the Controllers. See
+// Controller.Submit.
+func (p *Pool) Submit(task measurement.Task) *TaskHandle {
+	ctrl := p.pick(task)
+	if p.isDraining() {
+		return ctrl.rejectTask(task)
+	}
+	return ctrl.Submit(task)
+}
+
+// Like Submit, but the task is cancelled if ctx is done before the
+// task finishes.
+func (p *Pool) SubmitContext(ctx context.Context,
+	task measurement.Task) *TaskHandle {
+	ctrl := p.pick(task)
+	if p.isDraining() {
+		return ctrl.rejectTask(task)
+	}
+	return ctrl.SubmitContext(ctx, task)
+}
+
+// Submits a single task to one of the Controllers, and blocks until
+// its result is received. See Controller.Do.
+func (p *Pool) Do(ctx context.Context,
+	task measurement.Task) (measurement.Result, error) {
+	if p.isDraining() {
+		return nil, ErrControllerDraining
+	}
+	return p.pick(task).Do(ctx, task)
+}
+
+func (p *Pool) Outstanding() int {
+	total := 0
+	for _, ctrl := range p.ctrls {
+		total += ctrl.Outstanding()
+	}
+	return total
+}
+
+// Number of tasks that are being held back because their target is
+// busy. See Controller.Waiting.
+func (p *Pool) Waiting() int {
+	total := 0
+	for _, ctrl := range p.ctrls {
+		total += ctrl.Waiting()
+	}
+	return total
+}
+
+// Stops accepting new tasks, and drains each Controller. Queued tasks
+// that can't be handed to a Controller before ctx is done are returned
+// as abandoned. See Controller.Drain.
+func (p *Pool) Drain(ctx context.Context) error {
+	return p.router.drain(ctx)
+}
+
+func (p *Pool) Close() {
+	if p == nil {
+		return
+	}
+	p.router.close()
+}
+
+// private methods
+
+func (p *Pool) isDraining() bool {
+	return p.router.isDraining()
+}
+
+// Chooses the Controller to run the task on
+func (p *Pool) pick(task measurement.Task) *Controller {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.cfg.Strategy {
+	case POOL_LEAST_OUTSTANDING:
+		best, bestLoad := p.ctrls[0], -1
+		for _, ctrl := range p.ctrls {
+			load := ctrl.Outstanding() + len(ctrl.TaskQueue())
+			if bestLoad < 0 || load < bestLoad {
+				best, bestLoad = ctrl, load
+			}
+		}
+		return best
+
+	case POOL_TARGET_HASH:
+		h := fnv.New32a()
+		h.Write([]byte(task.Target))
+		return p.ctrls[h.Sum32()%uint32(len(p.ctrls))]
+	}
+
+	ctrl := p.ctrls[p.next]
+	p.next = (p.next + 1) % len(p.ctrls)
+	return ctrl
+}
@@ -0,0 +1,104 @@
+package scurry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alistairking/scurry/measurement"
+	"github.com/rs/zerolog"
+)
+
+func newTestPool(t *testing.T, cfg PoolConfig) *Pool {
+	t.Helper()
+	p, err := NewPool(zerolog.Nop(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPool(t *testing.T) {
+	a := (&fakeScamper{}).listen(t)
+	b := (&fakeScamper{}).listen(t)
+	p := newTestPool(t, PoolConfig{
+		Controllers: []ControllerConfig{{ScamperURL: a}, {ScamperURL: b}},
+		Strategy:    POOL_TARGET_HASH,
+	})
+	results := collectQueue(p.ResultQueue())
+
+	for i := 1; i <= 6; i++ {
+		p.TaskQueue() <- pingTask(fmt.Sprintf("192.0.2.%d", i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatalf("Drain() = %v", err)
+	}
+	p.Close()
+
+	tasks := <-results
+	if len(tasks) != 6 {
+		t.Fatalf("got %d results, want 6", len(tasks))
+	}
+	for _, task := range tasks {
+		if ping, ok := task.AsPing(); task.Status != measurement.TASK_COMPLETED ||
+			!ok || ping.Dst != task.Target {
+			t.Errorf("bad result: %+v", task)
+		}
+	}
+}
+
+// Drain must give up once its context is done, even if tasks are
+// still queued behind a Controller whose scamper is stuck
+func TestPoolDrainWithStuckScamper(t *testing.T) {
+	path := (&fakeScamper{stuck: true}).listen(t)
+	p := newTestPool(t, PoolConfig{
+		Controllers: []ControllerConfig{{
+			ScamperURL:   path,
+			Attach:       ScAttachConfig{CommandQueueLen: 1},
+			TaskQueueLen: 1,
+		}},
+	})
+	results := collectQueue(p.ResultQueue())
+
+	for i := 1; i <= 10; i++ {
+		p.TaskQueue() <- pingTask(fmt.Sprintf("192.0.2.%d", i))
+	}
+	// one command is waiting for a MORE, one fills the command
+	// queue, the controller's task handler is stuck on the third,
+	// the fourth fills its task queue, and the pool is stuck on the
+	// fifth
+	ctrl := p.Controllers()[0]
+	for ctrl.Outstanding() < 3 || len(ctrl.TaskQueue()) < 1 ||
+		len(p.TaskQueue()) > 5 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		300*time.Millisecond)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() {
+		drained <- p.Drain(ctx)
+	}()
+	select {
+	case err := <-drained:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Drain() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Drain() ignored its context")
+	}
+	p.Close()
+
+	tasks := <-results
+	if len(tasks) != 10 {
+		t.Fatalf("got %d results, want 10", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Status != measurement.TASK_ABANDONED {
+			t.Errorf("task towards %s: status %s", task.Target, task.Status)
+		}
+	}
+}
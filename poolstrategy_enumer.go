@@ -0,0 +1,80 @@
+// Code generated by "enumer -type=PoolStrategy -json -text -linecomment"; DO NOT EDIT.
+
+package scurry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _PoolStrategyName = "round-robinleast-outstandingtarget-hash"
+
+var _PoolStrategyIndex = [...]uint8{0, 11, 28, 39}
+
+func (i PoolStrategy) String() string {
+	if i >= PoolStrategy(len(_PoolStrategyIndex)-1) {
+		return fmt.Sprintf("PoolStrategy(%d)", i)
+	}
+	return _PoolStrategyName[_PoolStrategyIndex[i]:_PoolStrategyIndex[i+1]]
+}
+
+var _PoolStrategyValues = []PoolStrategy{0, 1, 2}
+
+var _PoolStrategyNameToValueMap = map[string]PoolStrategy{
+	_PoolStrategyName[0:11]:  0,
+	_PoolStrategyName[11:28]: 1,
+	_PoolStrategyName[28:39]: 2,
+}
+
+// PoolStrategyString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func PoolStrategyString(s string) (PoolStrategy, error) {
+	if val, ok := _PoolStrategyNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to PoolStrategy values", s)
+}
+
+// PoolStrategyValues returns all values of the enum
+func PoolStrategyValues() []PoolStrategy {
+	return _PoolStrategyValues
+}
+
+// IsAPoolStrategy returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i PoolStrategy) IsAPoolStrategy() bool {
+	for _, v := range _PoolStrategyValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for PoolStrategy
+func (i PoolStrategy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for PoolStrategy
+func (i *PoolStrategy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("PoolStrategy should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = PoolStrategyString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for PoolStrategy
+func (i PoolStrategy) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for PoolStrategy
+func (i *PoolStrategy) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = PoolStrategyString(string(text))
+	return err
+}
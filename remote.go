@@ -0,0 +1,696 @@
+package scurry
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Defaults for RemoteServerConfig
+const (
+	REMOTE_KEEPALIVE_INTERVAL = time.Second * 30
+	REMOTE_EVENT_Q_LEN        = 100
+	REMOTE_CHANNEL_BUF_LEN    = 1000
+)
+
+const (
+	// how long a newly connected scamper has to introduce itself
+	remoteHandshakeTimeout = time.Second * 10
+	// largest payload that fits in a single message
+	remoteMaxPayload = 0xffff
+	// longest monitor name that fits in a master-id message (its
+	// length byte includes the trailing NUL)
+	remoteMaxNameLen = 0xff - 1
+)
+
+// Control message types used by scamper's remote-control protocol.
+// These are sent on channel 0, and are identified by their first
+// byte.
+const (
+	remoteMasterNew  = 0 // scamper introducing itself
+	remoteMasterId   = 1 // our response to remoteMasterNew
+	remoteChannelNew = 2 // we open a channel
+	remoteChannelFin = 3 // either side closes a channel
+	remoteKeepalive  = 4
+)
+
+var (
+	ErrVantageNotConnected = errors.New("vantage point not connected")
+	errRemoteReplaced      = errors.New("replaced by a newer connection")
+	errRemoteClosed        = errors.New("remote server closed")
+)
+
+type RemoteServerConfig struct {
+	// Address (host:port) to listen on for connections from
+	// remote scamper instances (i.e., scamper -R host:port)
+	ListenAddr string
+	// If set, connections from scamper must use TLS
+	TLSConfig *tls.Config `json:"-"`
+
+	// How often to send keepalives to each scamper (default
+	// REMOTE_KEEPALIVE_INTERVAL)
+	KeepaliveInterval time.Duration
+	// If set, connections that we hear nothing from for this long
+	// are dropped. Zero means no limit.
+	IdleTimeout time.Duration
+	// Number of events that may be queued on the EventQueue
+	// (default REMOTE_EVENT_Q_LEN)
+	EventQueueLen int
+	// Number of messages from scamper that may be queued for each
+	// channel (default REMOTE_CHANNEL_BUF_LEN). A channel whose
+	// queue fills up is closed, so that it does not hold up the
+	// others.
+	ChannelBufferLen int
+}
+
+// Checks that the config is usable. Zero values are replaced with
+// defaults, so only the listen address is required.
+func (cfg RemoteServerConfig) Validate() error {
+	if cfg.ListenAddr == "" {
+		return fmt.Errorf("listen address must be set")
+	}
+	if cfg.KeepaliveInterval < 0 {
+		return fmt.Errorf("keepalive interval must not be negative")
+	}
+	if cfg.IdleTimeout < 0 {
+		return fmt.Errorf("idle timeout must not be negative")
+	}
+	if cfg.EventQueueLen < 0 {
+		return fmt.Errorf("event queue length must not be negative")
+	}
+	if cfg.ChannelBufferLen < 0 {
+		return fmt.Errorf("channel buffer length must not be negative")
+	}
+	return nil
+}
+
+func (cfg RemoteServerConfig) withDefaults() RemoteServerConfig {
+	if cfg.KeepaliveInterval == 0 {
+		cfg.KeepaliveInterval = REMOTE_KEEPALIVE_INTERVAL
+	}
+	if cfg.EventQueueLen == 0 {
+		cfg.EventQueueLen = REMOTE_EVENT_Q_LEN
+	}
+	if cfg.ChannelBufferLen == 0 {
+		cfg.ChannelBufferLen = REMOTE_CHANNEL_BUF_LEN
+	}
+	return cfg
+}
+
+// A remote scamper connecting to, or disconnecting from, a
+// RemoteServer
+type RemoteEvent struct {
+	Name  string    // the vantage point's name
+	Addr  net.Addr  // where scamper connected from
+	State ConnState // CONN_CONNECTED or CONN_DISCONNECTED
+	Err   error     // why the connection dropped
+}
+
+// Central server for remote scamper instances, in the style of
+// scamper's sc_remoted. Scamper instances started with `-R host:port`
+// connect to the server, and each becomes a vantage point, named for
+// its monitor name (scamper -M) if it has one, or else for the address
+// it connected from.
+//
+// Scamper multiplexes many control "channels" over its single
+// connection. Each call to Dial opens a new channel, which behaves
+// just like a connection to scamper's control socket, so AttachConfig
+// (or VantagePoint) can be used to drive a remote scamper with an
+// ScAttach, Controller or MultiController. These reconnect (with a new
+// channel) if the remote scamper reconnects.
+type RemoteServer struct {
+	log Logger
+	cfg RemoteServerConfig
+	ln  net.Listener
+
+	mu      *sync.Mutex // protects masters and closed
+	masters map[string]*remoteMaster
+	closed  bool
+
+	eventQ chan RemoteEvent
+	done   chan struct{} // closed by Close
+	wg     *sync.WaitGroup
+}
+
+func NewRemoteServer(log zerolog.Logger, cfg RemoteServerConfig) (*RemoteServer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TLSConfig != nil {
+		ln = tls.NewListener(ln, cfg.TLSConfig)
+	}
+
+	s := &RemoteServer{
+		log:     initLogger(log, "remote"),
+		cfg:     cfg,
+		ln:      ln,
+		mu:      &sync.Mutex{},
+		masters: map[string]*remoteMaster{},
+		eventQ:  make(chan RemoteEvent, cfg.EventQueueLen),
+		done:    make(chan struct{}),
+		wg:      &sync.WaitGroup{},
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop()
+
+	s.log.Info().
+		Str("addr", ln.Addr().String()).
+		Bool("tls", cfg.TLSConfig != nil).
+		Msgf("Listening for remote scamper connections")
+
+	return s, nil
+}
+
+// The address the server is listening on
+func (s *RemoteServer) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Remote scamper instances connecting and disconnecting. Servicing
+// this queue is optional: events are dropped if it fills up.
+func (s *RemoteServer) EventQueue() chan RemoteEvent {
+	return s.eventQ
+}
+
+// The names of the currently connected vantage points
+func (s *RemoteServer) VantagePoints() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.masters))
+	for name := range s.masters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Opens a new control channel to the named vantage point
+func (s *RemoteServer) Dial(name string) (net.Conn, error) {
+	s.mu.Lock()
+	m := s.masters[name]
+	s.mu.Unlock()
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVantageNotConnected, name)
+	}
+	return m.openChannel()
+}
+
+// Returns an ScAttachConfig that attaches to the named vantage point.
+// The vantage point need not be connected yet: ScAttach keeps trying
+// until it is.
+func (s *RemoteServer) AttachConfig(name string) ScAttachConfig {
+	return ScAttachConfig{
+		URL: fmt.Sprintf("remote:%s", name),
+		Dial: func() (net.Conn, error) {
+			return s.Dial(name)
+		},
+	}
+}
+
+// Returns a VantagePointConfig (for a MultiController) for the named
+// vantage point
+func (s *RemoteServer) VantagePoint(name string) VantagePointConfig {
+	return VantagePointConfig{
+		Name:       name,
+		Controller: ControllerConfig{Attach: s.AttachConfig(name)},
+	}
+}
+
+// Stops listening, and drops all remote scamper connections. The
+// EventQueue is closed once Close completes.
+func (s *RemoteServer) Close() {
+	s.mu.Lock()
+	s.closed = true
+	masters := s.masters
+	s.masters = map[string]*remoteMaster{}
+	s.mu.Unlock()
+
+	close(s.done)
+	s.ln.Close()
+	for _, m := range masters {
+		m.shutdown(errRemoteClosed)
+	}
+	s.wg.Wait()
+	close(s.eventQ)
+	s.log.Debug().Msgf("Shutdown complete")
+}
+
+// private methods
+
+func (s *RemoteServer) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return
+			}
+			s.log.Error().
+				Err(err).
+				Msgf("Failed to accept remote scamper connection")
+			time.Sleep(time.Millisecond * 100)
+			continue
+		}
+		s.wg.Add(1)
+		go s.serveMaster(conn)
+	}
+}
+
+func (s *RemoteServer) emit(ev RemoteEvent) {
+	select {
+	case s.eventQ <- ev:
+	default:
+		s.log.Warn().Msgf("Event queue full, dropping event")
+	}
+}
+
+// Handles a single remote scamper connection
+func (s *RemoteServer) serveMaster(conn net.Conn) {
+	defer s.wg.Done()
+	log := s.log.With().
+		Str("remote-addr", conn.RemoteAddr().String()).
+		Logger()
+	r := bufio.NewReader(conn)
+
+	// scamper must introduce itself first
+	handshook := make(chan struct{})
+	go func() {
+		select {
+		case <-s.done:
+			conn.Close()
+		case <-handshook:
+		}
+	}()
+	conn.SetReadDeadline(time.Now().Add(remoteHandshakeTimeout))
+	magic, name, err := readMasterNew(r)
+	close(handshook)
+	if err != nil {
+		select {
+		case <-s.done:
+			// closed while waiting
+			conn.Close()
+			return
+		default:
+		}
+		log.Error().
+			Err(err).
+			Msgf("Bad handshake from remote scamper")
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	if name == "" {
+		name = conn.RemoteAddr().String()
+		if host, _, err := net.SplitHostPort(name); err == nil {
+			name = host
+		}
+	}
+
+	m := newRemoteMaster(log, name, magic, conn, s.cfg.ChannelBufferLen)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	prev := s.masters[name]
+	s.masters[name] = m
+	s.mu.Unlock()
+	if prev != nil {
+		log.Info().
+			Str("name", name).
+			Bool("same-scamper", bytes.Equal(prev.magic, magic)).
+			Msgf("Remote scamper reconnected, dropping previous connection")
+		prev.shutdown(errRemoteReplaced)
+	}
+
+	err = m.writeControl(remoteMasterId, append([]byte{byte(len(name) + 1)},
+		append([]byte(name), 0)...))
+	if err == nil {
+		log.Info().
+			Str("name", name).
+			Msgf("Remote scamper connected")
+		s.emit(RemoteEvent{
+			Name:  name,
+			Addr:  conn.RemoteAddr(),
+			State: CONN_CONNECTED,
+		})
+		go m.keepalive(s.cfg.KeepaliveInterval)
+		err = m.serve(r, s.cfg.IdleTimeout)
+	}
+	m.shutdown(err)
+	err = m.closeReason()
+
+	s.mu.Lock()
+	current := s.masters[name] == m
+	if current {
+		delete(s.masters, name)
+	}
+	s.mu.Unlock()
+	if err == errRemoteReplaced || err == errRemoteClosed {
+		// not worth shouting about
+		err = nil
+	}
+	log.Info().
+		Str("name", name).
+		AnErr("reason", err).
+		Msgf("Remote scamper disconnected")
+	if current {
+		s.emit(RemoteEvent{
+			Name:  name,
+			Addr:  conn.RemoteAddr(),
+			State: CONN_DISCONNECTED,
+			Err:   err,
+		})
+	}
+}
+
+// Reads a single message from a remote scamper
+func readRemoteMsg(r io.Reader) (uint32, []byte, error) {
+	hdr := make([]byte, 6)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, nil, err
+	}
+	chanId := binary.BigEndian.Uint32(hdr[0:4])
+	payload := make([]byte, binary.BigEndian.Uint16(hdr[4:6]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return chanId, payload, nil
+}
+
+// Reads scamper's introduction, and returns its magic and (optional)
+// monitor name
+func readMasterNew(r io.Reader) ([]byte, string, error) {
+	chanId, msg, err := readRemoteMsg(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if chanId != 0 || len(msg) < 2 || msg[0] != remoteMasterNew {
+		return nil, "", fmt.Errorf("expected master-new message")
+	}
+	magicLen := int(msg[1])
+	if len(msg) < 2+magicLen {
+		return nil, "", fmt.Errorf("truncated master-new message")
+	}
+	magic := msg[2 : 2+magicLen]
+	rest := msg[2+magicLen:]
+	if len(rest) == 0 {
+		return magic, "", nil
+	}
+	nameLen := int(rest[0])
+	if len(rest) < 1+nameLen {
+		return nil, "", fmt.Errorf("truncated master-new message")
+	}
+	name := bytes.TrimRight(rest[1:1+nameLen], "\x00")
+	if len(name) > remoteMaxNameLen {
+		return nil, "", fmt.Errorf("monitor name longer than %d bytes",
+			remoteMaxNameLen)
+	}
+	return magic, string(name), nil
+}
+
+// A single remote scamper connection, over which scamper multiplexes
+// many control channels
+type remoteMaster struct {
+	log   Logger
+	name  string
+	magic []byte // random bytes chosen by scamper
+	conn  net.Conn
+	txMu  *sync.Mutex // serializes writes to conn
+
+	mu       *sync.Mutex // protects channels, nextChan, closed and reason
+	channels map[uint32]*remoteChannel
+	nextChan uint32
+	closed   bool
+	reason   error // why the connection was closed
+	done     chan struct{}
+	bufLen   int // see RemoteServerConfig.ChannelBufferLen
+}
+
+// A single control channel to a remote scamper. Data from scamper is
+// queued on rx, so that a slow reader only holds up its own channel.
+type remoteChannel struct {
+	conn net.Conn      // our end of the pipe handed out by Dial
+	rx   chan []byte   // data from scamper, waiting to be written to conn
+	done chan struct{} // closed once the channel is closed
+}
+
+func newRemoteMaster(log Logger, name string, magic []byte,
+	conn net.Conn, bufLen int) *remoteMaster {
+	return &remoteMaster{
+		log:      log.With().Str("name", name).Logger(),
+		name:     name,
+		magic:    magic,
+		conn:     conn,
+		txMu:     &sync.Mutex{},
+		mu:       &sync.Mutex{},
+		channels: map[uint32]*remoteChannel{},
+		nextChan: 1, // channel 0 is for control messages
+		done:     make(chan struct{}),
+		bufLen:   bufLen,
+	}
+}
+
+func (ch *remoteChannel) close() {
+	close(ch.done)
+	ch.conn.Close()
+}
+
+// Handles messages from scamper until the connection drops
+func (m *remoteMaster) serve(r io.Reader, idleTimeout time.Duration) error {
+	for {
+		if idleTimeout > 0 {
+			m.conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		}
+		chanId, msg, err := readRemoteMsg(r)
+		if err != nil {
+			return err
+		}
+		if chanId != 0 {
+			m.handleData(chanId, msg)
+			continue
+		}
+		if len(msg) == 0 {
+			return fmt.Errorf("empty control message")
+		}
+		switch msg[0] {
+		case remoteChannelFin:
+			if len(msg) < 5 {
+				return fmt.Errorf("truncated channel-fin message")
+			}
+			m.closeChannel(binary.BigEndian.Uint32(msg[1:5]), false)
+
+		case remoteKeepalive:
+			// nothing to do
+
+		default:
+			return fmt.Errorf("unexpected control message type %d", msg[0])
+		}
+	}
+}
+
+// Hands data from scamper to whoever opened the channel
+func (m *remoteMaster) handleData(chanId uint32, data []byte) {
+	m.mu.Lock()
+	ch := m.channels[chanId]
+	m.mu.Unlock()
+	if ch == nil {
+		m.log.Debug().
+			Uint32("channel", chanId).
+			Msgf("Discarding data for unknown channel")
+		return
+	}
+	select {
+	case ch.rx <- data:
+	default:
+		// rather than stall every other channel (and our
+		// control messages), give up on this one. whoever
+		// opened it will see it close, and can open another.
+		m.log.Warn().
+			Uint32("channel", chanId).
+			Int("queued", len(ch.rx)).
+			Msgf("Channel not being serviced, closing it")
+		m.closeChannel(chanId, true)
+	}
+}
+
+// Opens a new channel, and returns our end of it
+func (m *remoteMaster) openChannel() (net.Conn, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrVantageNotConnected, m.name)
+	}
+	chanId := m.nextChan
+	m.nextChan++
+	local, conn := net.Pipe()
+	ch := &remoteChannel{
+		conn: conn,
+		rx:   make(chan []byte, m.bufLen),
+		done: make(chan struct{}),
+	}
+	m.channels[chanId] = ch
+	m.mu.Unlock()
+
+	msg := make([]byte, 4)
+	binary.BigEndian.PutUint32(msg, chanId)
+	if err := m.writeControl(remoteChannelNew, msg); err != nil {
+		m.closeChannel(chanId, false)
+		local.Close()
+		return nil, err
+	}
+	m.log.Debug().
+		Uint32("channel", chanId).
+		Msgf("Opened channel")
+
+	go m.channelRx(ch)
+	go m.channelTx(chanId, ch.conn)
+	return local, nil
+}
+
+// Hands data queued from scamper to whoever opened the channel, until
+// the channel is closed
+func (m *remoteMaster) channelRx(ch *remoteChannel) {
+	for {
+		select {
+		case data := <-ch.rx:
+			if _, err := ch.conn.Write(data); err != nil {
+				return
+			}
+		case <-ch.done:
+			return
+		}
+	}
+}
+
+// Forwards data written to a channel on to scamper, until the channel
+// is closed
+func (m *remoteMaster) channelTx(chanId uint32, ch net.Conn) {
+	buf := make([]byte, remoteMaxPayload)
+	for {
+		n, err := ch.Read(buf)
+		if n > 0 {
+			if werr := m.writeMsg(chanId, buf[:n]); werr != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	m.closeChannel(chanId, true)
+}
+
+// Forgets a channel, and tells scamper about it if sendFin is set
+func (m *remoteMaster) closeChannel(chanId uint32, sendFin bool) {
+	m.mu.Lock()
+	ch := m.channels[chanId]
+	delete(m.channels, chanId)
+	closed := m.closed
+	m.mu.Unlock()
+	if ch == nil {
+		return
+	}
+	ch.close()
+	m.log.Debug().
+		Uint32("channel", chanId).
+		Bool("by-scamper", !sendFin).
+		Msgf("Closed channel")
+	if sendFin && !closed {
+		msg := make([]byte, 4)
+		binary.BigEndian.PutUint32(msg, chanId)
+		m.writeControl(remoteChannelFin, msg)
+	}
+}
+
+func (m *remoteMaster) writeControl(msgType byte, body []byte) error {
+	return m.writeMsg(0, append([]byte{msgType}, body...))
+}
+
+func (m *remoteMaster) writeMsg(chanId uint32, payload []byte) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	for {
+		n := len(payload)
+		if n > remoteMaxPayload {
+			n = remoteMaxPayload
+		}
+		msg := make([]byte, 6+n)
+		binary.BigEndian.PutUint32(msg[0:4], chanId)
+		binary.BigEndian.PutUint16(msg[4:6], uint16(n))
+		copy(msg[6:], payload[:n])
+		if _, err := m.conn.Write(msg); err != nil {
+			return err
+		}
+		payload = payload[n:]
+		if len(payload) == 0 {
+			return nil
+		}
+	}
+}
+
+func (m *remoteMaster) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.writeControl(remoteKeepalive, nil); err != nil {
+				return
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *remoteMaster) closeReason() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reason
+}
+
+// Drops the connection to scamper, and closes all of its channels
+func (m *remoteMaster) shutdown(reason error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.reason = reason
+	channels := m.channels
+	m.channels = map[uint32]*remoteChannel{}
+	m.mu.Unlock()
+
+	close(m.done)
+	m.conn.Close()
+	for _, ch := range channels {
+		ch.close()
+	}
+	m.log.Debug().
+		AnErr("reason", reason).
+		Int("channels", len(channels)).
+		Msgf("Connection closed")
+}
@@ -0,0 +1,331 @@
+package scurry
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alistairking/scurry/measurement"
+	"github.com/rs/zerolog"
+)
+
+// A fake remote scamper (i.e., scamper -R), which connects to a
+// RemoteServer and serves a fakeScamper on each channel that the
+// server opens
+type fakeRemote struct {
+	t     *testing.T
+	conn  net.Conn
+	scamp *fakeScamper
+
+	mu       sync.Mutex
+	channels map[uint32]net.Conn
+	opened   chan uint32 // channels opened by the server
+	finned   chan uint32 // channels closed by the server
+}
+
+// Connects to the server and introduces itself, returning the
+// MASTER_ID payload that the server responded with
+func dialFakeRemote(t *testing.T, addr string, name string) (*fakeRemote, []byte) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRemote{
+		t:        t,
+		conn:     conn,
+		scamp:    &fakeScamper{},
+		channels: map[uint32]net.Conn{},
+		opened:   make(chan uint32, 10),
+		finned:   make(chan uint32, 10),
+	}
+	t.Cleanup(f.close)
+
+	magic := []byte("0123456789abcdef")
+	msg := append([]byte{remoteMasterNew, byte(len(magic))}, magic...)
+	if name != "" {
+		msg = append(msg, byte(len(name)+1))
+		msg = append(msg, name...)
+		msg = append(msg, 0)
+	}
+	f.write(0, msg)
+
+	r := bufio.NewReader(conn)
+	chanId, id, err := readRemoteMsg(r)
+	if err != nil {
+		t.Fatalf("no master-id from server: %v", err)
+	}
+	if chanId != 0 || len(id) == 0 || id[0] != remoteMasterId {
+		t.Fatalf("expected master-id, got %v on channel %d", id, chanId)
+	}
+	go f.serve(r)
+	return f, id[1:]
+}
+
+func (f *fakeRemote) write(chanId uint32, payload []byte) error {
+	hdr := make([]byte, 6)
+	binary.BigEndian.PutUint32(hdr[0:4], chanId)
+	binary.BigEndian.PutUint16(hdr[4:6], uint16(len(payload)))
+	_, err := f.conn.Write(append(hdr, payload...))
+	return err
+}
+
+func (f *fakeRemote) writeControl(msgType byte, chanId uint32) error {
+	msg := make([]byte, 5)
+	msg[0] = msgType
+	binary.BigEndian.PutUint32(msg[1:5], chanId)
+	return f.write(0, msg)
+}
+
+// Handles messages from the server until the connection drops
+func (f *fakeRemote) serve(r io.Reader) {
+	for {
+		chanId, msg, err := readRemoteMsg(r)
+		if err != nil {
+			f.close()
+			return
+		}
+		if chanId != 0 {
+			f.mu.Lock()
+			ch := f.channels[chanId]
+			f.mu.Unlock()
+			if ch != nil {
+				ch.Write(msg)
+			}
+			continue
+		}
+		switch msg[0] {
+		case remoteChannelNew:
+			chanId := binary.BigEndian.Uint32(msg[1:5])
+			local, ch := net.Pipe()
+			f.mu.Lock()
+			f.channels[chanId] = ch
+			f.mu.Unlock()
+			go f.scamp.serve(local)
+			go f.channelTx(chanId, ch)
+			f.opened <- chanId
+
+		case remoteChannelFin:
+			chanId := binary.BigEndian.Uint32(msg[1:5])
+			f.mu.Lock()
+			ch := f.channels[chanId]
+			delete(f.channels, chanId)
+			f.mu.Unlock()
+			if ch != nil {
+				ch.Close()
+			}
+			f.finned <- chanId
+
+		case remoteKeepalive:
+		}
+	}
+}
+
+// Forwards the fake scamper's responses to the server
+func (f *fakeRemote) channelTx(chanId uint32, ch net.Conn) {
+	buf := make([]byte, remoteMaxPayload)
+	for {
+		n, err := ch.Read(buf)
+		if n > 0 {
+			f.write(chanId, buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Closes a channel from scamper's side
+func (f *fakeRemote) finChannel(chanId uint32) {
+	f.mu.Lock()
+	ch := f.channels[chanId]
+	delete(f.channels, chanId)
+	f.mu.Unlock()
+	if ch != nil {
+		ch.Close()
+	}
+	f.writeControl(remoteChannelFin, chanId)
+}
+
+func (f *fakeRemote) close() {
+	f.conn.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.channels {
+		ch.Close()
+	}
+	f.channels = map[uint32]net.Conn{}
+}
+
+func newTestRemoteServer(t *testing.T, cfg RemoteServerConfig) *RemoteServer {
+	t.Helper()
+	cfg.ListenAddr = "127.0.0.1:0"
+	s, err := NewRemoteServer(zerolog.Nop(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func waitRemoteEvent(t *testing.T, s *RemoteServer, name string,
+	state ConnState) {
+	t.Helper()
+	for {
+		select {
+		case ev := <-s.EventQueue():
+			if ev.Name == name && ev.State == state {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event for %s", state, name)
+		}
+	}
+}
+
+func waitChannel(t *testing.T, ch chan uint32, what string) uint32 {
+	t.Helper()
+	select {
+	case chanId := <-ch:
+		return chanId
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no channel %s", what)
+	}
+	return 0
+}
+
+func remotePing(t *testing.T, ctrl *Controller, target string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := ctrl.Do(ctx, pingTask(target))
+	if err != nil {
+		t.Fatalf("ping via remote scamper failed: %v", err)
+	}
+	if ping, ok := res.(*measurement.PingResult); !ok || ping.Dst != target {
+		t.Fatalf("bad result: %v", res)
+	}
+}
+
+func TestRemoteServer(t *testing.T) {
+	s := newTestRemoteServer(t, RemoteServerConfig{})
+	addr := s.Addr().String()
+
+	// handshake
+	remote, id := dialFakeRemote(t, addr, "vp1")
+	if want := []byte("\x04vp1\x00"); !reflect.DeepEqual(id, want) {
+		t.Errorf("master-id = %q, want %q", id, want)
+	}
+	waitRemoteEvent(t, s, "vp1", CONN_CONNECTED)
+	if got := s.VantagePoints(); !reflect.DeepEqual(got, []string{"vp1"}) {
+		t.Errorf("VantagePoints() = %v", got)
+	}
+	if _, err := s.Dial("vp2"); err == nil {
+		t.Errorf("Dial() of an unknown vantage point succeeded")
+	}
+
+	// attaching opens a channel, over which we can ping
+	attachCfg := s.AttachConfig("vp1")
+	attachCfg.ReconnectBackoff = 10 * time.Millisecond
+	ctrl := newTestController(t, ControllerConfig{Attach: attachCfg})
+	first := waitChannel(t, remote.opened, "opened")
+	remotePing(t, ctrl, "192.0.2.1")
+
+	// if scamper closes the channel, ScAttach reattaches on a new
+	// one
+	remote.finChannel(first)
+	if second := waitChannel(t, remote.opened, "reopened"); second == first {
+		t.Errorf("channel %d was reused", second)
+	}
+	remotePing(t, ctrl, "192.0.2.2")
+
+	// if scamper reconnects, so do we
+	remote.close()
+	waitRemoteEvent(t, s, "vp1", CONN_DISCONNECTED)
+	remote, _ = dialFakeRemote(t, addr, "vp1")
+	waitRemoteEvent(t, s, "vp1", CONN_CONNECTED)
+	waitChannel(t, remote.opened, "opened after reconnecting")
+	remotePing(t, ctrl, "192.0.2.3")
+
+	// and closing the controller closes its channel
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctrl.Drain(ctx)
+	ctrl.Close()
+	waitChannel(t, remote.finned, "closed")
+}
+
+func TestRemoteServerUnnamed(t *testing.T) {
+	s := newTestRemoteServer(t, RemoteServerConfig{})
+	_, id := dialFakeRemote(t, s.Addr().String(), "")
+	if want := []byte("\x0a127.0.0.1\x00"); !reflect.DeepEqual(id, want) {
+		t.Errorf("master-id = %q, want %q", id, want)
+	}
+	waitRemoteEvent(t, s, "127.0.0.1", CONN_CONNECTED)
+}
+
+// A channel that isn't being read from must not hold up the others
+func TestRemoteServerSlowChannel(t *testing.T) {
+	s := newTestRemoteServer(t, RemoteServerConfig{ChannelBufferLen: 4})
+	remote, _ := dialFakeRemote(t, s.Addr().String(), "vp1")
+	waitRemoteEvent(t, s, "vp1", CONN_CONNECTED)
+
+	slow, err := s.Dial("vp1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+	slowId := waitChannel(t, remote.opened, "opened")
+	for i := 0; i < 10; i++ {
+		remote.write(slowId, []byte("MORE\n"))
+	}
+	if finned := waitChannel(t, remote.finned, "closed"); finned != slowId {
+		t.Errorf("closed channel %d, want %d", finned, slowId)
+	}
+
+	ctrl := newTestController(t, ControllerConfig{
+		Attach: s.AttachConfig("vp1"),
+	})
+	remotePing(t, ctrl, "192.0.2.1")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctrl.Drain(ctx)
+	ctrl.Close()
+}
+
+func TestRemoteServerLongName(t *testing.T) {
+	s := newTestRemoteServer(t, RemoteServerConfig{})
+
+	name := strings.Repeat("x", remoteMaxNameLen)
+	_, id := dialFakeRemote(t, s.Addr().String(), name)
+	if want := append([]byte{0xff}, name+"\x00"...); !reflect.DeepEqual(id, want) {
+		t.Errorf("master-id = %q, want %q", id, want)
+	}
+
+	// one more byte, and the name (with its NUL) won't fit in the
+	// master-id message, so the server hangs up
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	name += "x"
+	msg := []byte{remoteMasterNew, 1, 0, byte(len(name))}
+	msg = append(msg, name...)
+	hdr := make([]byte, 6)
+	binary.BigEndian.PutUint16(hdr[4:6], uint16(len(msg)))
+	if _, err := conn.Write(append(hdr, msg...)); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := readRemoteMsg(conn); err != io.EOF {
+		t.Errorf("expected the server to hang up, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Code generated by "enumer -type=ReplayPolicy -json -text -linecomment"; DO NOT EDIT.
+
+package scurry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const _ReplayPolicyName = "allunacceptednone"
+
+var _ReplayPolicyIndex = [...]uint8{0, 3, 13, 17}
+
+func (i ReplayPolicy) String() string {
+	if i >= ReplayPolicy(len(_ReplayPolicyIndex)-1) {
+		return fmt.Sprintf("ReplayPolicy(%d)", i)
+	}
+	return _ReplayPolicyName[_ReplayPolicyIndex[i]:_ReplayPolicyIndex[i+1]]
+}
+
+var _ReplayPolicyValues = []ReplayPolicy{0, 1, 2}
+
+var _ReplayPolicyNameToValueMap = map[string]ReplayPolicy{
+	_ReplayPolicyName[0:3]:   0,
+	_ReplayPolicyName[3:13]:  1,
+	_ReplayPolicyName[13:17]: 2,
+}
+
+// ReplayPolicyString retrieves an enum value from the enum constants string name.
+// Throws an error if the param is not part of the enum.
+func ReplayPolicyString(s string) (ReplayPolicy, error) {
+	if val, ok := _ReplayPolicyNameToValueMap[s]; ok {
+		return val, nil
+	}
+	return 0, fmt.Errorf("%s does not belong to ReplayPolicy values", s)
+}
+
+// ReplayPolicyValues returns all values of the enum
+func ReplayPolicyValues() []ReplayPolicy {
+	return _ReplayPolicyValues
+}
+
+// IsAReplayPolicy returns "true" if the value is listed in the enum definition. "false" otherwise
+func (i ReplayPolicy) IsAReplayPolicy() bool {
+	for _, v := range _ReplayPolicyValues {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements the json.Marshaler interface for ReplayPolicy
+func (i ReplayPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for ReplayPolicy
+func (i *ReplayPolicy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("ReplayPolicy should be a string, got %s", data)
+	}
+
+	var err error
+	*i, err = ReplayPolicyString(s)
+	return err
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for ReplayPolicy
+func (i ReplayPolicy) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for ReplayPolicy
+func (i *ReplayPolicy) UnmarshalText(text []byte) error {
+	var err error
+	*i, err = ReplayPolicyString(string(text))
+	return err
+}
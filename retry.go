@@ -0,0 +1,82 @@
+package scurry
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alistairking/scurry/measurement"
+)
+
+// Statuses that are retried if RetryPolicy.RetryOn is not set
+var defaultRetryOn = []measurement.TaskStatus{
+	measurement.TASK_REJECTED,
+	measurement.TASK_TIMED_OUT,
+}
+
+// Controls whether (and how) the Controller retries failed tasks. Each
+// retry is sent to scamper with a fresh UserId, and the outcome of the
+// failed attempt is recorded in Task.Attempts. Only the final outcome
+// is returned.
+//
+// The zero value disables retries.
+type RetryPolicy struct {
+	// Maximum number of attempts to make at each task, including
+	// the first. Values less than 2 disable retries.
+	MaxAttempts int
+	// How long to wait before the first retry. The wait doubles
+	// with each subsequent retry, up to MaxBackoff (if set).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Task statuses that should be retried. If unset, rejected and
+	// timed-out tasks are retried. Cancelled and abandoned tasks
+	// are never retried.
+	RetryOn []measurement.TaskStatus
+}
+
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("retry max attempts must not be negative")
+	}
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("retry backoff must not be negative")
+	}
+	return nil
+}
+
+func (p RetryPolicy) shouldRetry(task measurement.Task, now time.Time) bool {
+	if len(task.Attempts)+1 >= p.MaxAttempts {
+		return false
+	}
+	switch task.Status {
+	case measurement.TASK_CANCELLED, measurement.TASK_ABANDONED:
+		return false
+	}
+	if !task.Deadline.IsZero() && !now.Before(task.Deadline) {
+		// out of time
+		return false
+	}
+	retryOn := p.RetryOn
+	if retryOn == nil {
+		retryOn = defaultRetryOn
+	}
+	for _, status := range retryOn {
+		if status == task.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns how long to wait before making the given retry (starting
+// from 1).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
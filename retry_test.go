@@ -0,0 +1,145 @@
+package scurry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alistairking/scurry/measurement"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{
+		Backoff:    10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	}
+	for i, want := range []time.Duration{10, 20, 40, 50, 50} {
+		want *= time.Millisecond
+		if got := p.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+// Runs the tasks through a controller, and returns everything that
+// comes back on the ResultQueue
+func runTasks(t *testing.T, cfg ControllerConfig,
+	tasks ...measurement.Task) []measurement.Task {
+	t.Helper()
+	ctrl := newTestController(t, cfg)
+	results := collectResults(ctrl)
+	for _, task := range tasks {
+		ctrl.TaskQueue() <- task
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ctrl.Drain(ctx); err != nil {
+		t.Errorf("Drain() = %v", err)
+	}
+	ctrl.Close()
+	return <-results
+}
+
+// Checks that successive pings towards the target were at least the
+// given delays apart
+func checkGaps(t *testing.T, f *fakeScamper, target string,
+	gaps ...time.Duration) {
+	t.Helper()
+	times := f.pingTimes(target)
+	if len(times) != len(gaps)+1 {
+		t.Fatalf("%d pings towards %s, want %d", len(times), target,
+			len(gaps)+1)
+	}
+	for i, gap := range gaps {
+		if got := times[i+1].Sub(times[i]); got < gap {
+			t.Errorf("attempt %d towards %s sent after %v, want at least %v",
+				i+2, target, got, gap)
+		}
+	}
+}
+
+func TestControllerRetry(t *testing.T) {
+	f := &fakeScamper{rejects: map[string]int{
+		"192.0.2.1": 2, // succeeds on the last attempt
+		"192.0.2.2": 5, // never succeeds
+	}}
+	tasks := runTasks(t, ControllerConfig{
+		ScamperURL: f.listen(t),
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+		},
+	}, pingTask("192.0.2.1"), pingTask("192.0.2.2"))
+
+	// only the final outcome of each task is returned
+	if len(tasks) != 2 {
+		t.Fatalf("got %d results, want 2", len(tasks))
+	}
+	for _, task := range tasks {
+		want := measurement.TASK_COMPLETED
+		if task.Target == "192.0.2.2" {
+			want = measurement.TASK_REJECTED
+		}
+		if task.Status != want {
+			t.Errorf("task towards %s: status %s, want %s",
+				task.Target, task.Status, want)
+		}
+		if len(task.Attempts) != 2 {
+			t.Fatalf("task towards %s: %d previous attempts, want 2",
+				task.Target, len(task.Attempts))
+		}
+		seen := map[uint64]bool{task.UserId: true}
+		for _, a := range task.Attempts {
+			if a.Status != measurement.TASK_REJECTED ||
+				a.Error != "target unreachable" || seen[a.UserId] {
+				t.Errorf("task towards %s: bad attempt %+v",
+					task.Target, a)
+			}
+			seen[a.UserId] = true
+		}
+		if ping, ok := task.AsPing(); want == measurement.TASK_COMPLETED &&
+			(!ok || ping.UserID != task.UserId) {
+			t.Errorf("task towards %s: bad result %v", task.Target,
+				task.Result)
+		}
+	}
+
+	// the backoff doubles with each retry
+	checkGaps(t, f, "192.0.2.1", 100*time.Millisecond, 200*time.Millisecond)
+	checkGaps(t, f, "192.0.2.2", 100*time.Millisecond, 200*time.Millisecond)
+}
+
+// A retry that is held back by MaxPerTarget must still wait out its
+// backoff once the target is free
+func TestControllerRetryMaxPerTarget(t *testing.T) {
+	f := &fakeScamper{rejects: map[string]int{"192.0.2.1": 1}}
+	first, second := pingTask("192.0.2.1"), pingTask("192.0.2.1")
+	first.Id, second.Id = "first", "second"
+	tasks := runTasks(t, ControllerConfig{
+		ScamperURL:   f.listen(t),
+		MaxPerTarget: 1,
+		Retry: RetryPolicy{
+			MaxAttempts: 2,
+			Backoff:     300 * time.Millisecond,
+		},
+	}, first, second)
+
+	if len(tasks) != 2 {
+		t.Fatalf("got %d results, want 2", len(tasks))
+	}
+	for _, task := range tasks {
+		wantAttempts := 0
+		if task.Id == "first" {
+			wantAttempts = 1
+		}
+		if task.Status != measurement.TASK_COMPLETED ||
+			len(task.Attempts) != wantAttempts {
+			t.Errorf("task %s: status %s after %d attempts", task.Id,
+				task.Status, len(task.Attempts)+1)
+		}
+	}
+	// the first is rejected, the second is released straight away
+	// (holding back the retry), and the retry follows once its
+	// backoff has passed
+	checkGaps(t, f, "192.0.2.1", 0, 250*time.Millisecond)
+}
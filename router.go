@@ -0,0 +1,177 @@
+package scurry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alistairking/scurry/measurement"
+)
+
+// Plumbing shared by the types that front several Controllers (Pool
+// and MultiController): a TaskQueue whose tasks are routed to the
+// Controllers, and a ResultQueue onto which their results are merged.
+type ctrlRouter struct {
+	log   Logger
+	ctrls []*Controller
+
+	mu       *sync.Mutex
+	draining bool
+
+	taskQ      chan measurement.Task
+	taskCancel context.CancelFunc
+	taskWg     *sync.WaitGroup
+	abandoned  int // tasks that Drain gave up handing off
+
+	// cancelled if Drain gives up before the queued tasks have
+	// been handed off
+	sendCtx    context.Context
+	sendCancel context.CancelFunc
+
+	resQ  chan measurement.Task
+	resWg *sync.WaitGroup
+}
+
+func newCtrlRouter(log Logger, ctrls []*Controller, taskQLen int,
+	resQLen int) *ctrlRouter {
+	sendCtx, sendCancel := context.WithCancel(context.Background())
+	return &ctrlRouter{
+		log:   log,
+		ctrls: ctrls,
+		mu:    &sync.Mutex{},
+
+		taskQ:  make(chan measurement.Task, taskQLen),
+		taskWg: &sync.WaitGroup{},
+
+		sendCtx:    sendCtx,
+		sendCancel: sendCancel,
+
+		resQ:  make(chan measurement.Task, resQLen),
+		resWg: &sync.WaitGroup{},
+	}
+}
+
+// Starts merging results, and handing each queued task to route,
+// which should pass it on using handoff (or fail).
+func (r *ctrlRouter) start(route func(task measurement.Task)) {
+	for _, ctrl := range r.ctrls {
+		r.resWg.Add(1)
+		go r.resultForwarder(ctrl)
+	}
+	// the task handler may also return tasks, so it holds the
+	// result queue open too
+	r.resWg.Add(1)
+	go func() {
+		r.resWg.Wait()
+		close(r.resQ)
+	}()
+
+	taskCtx, taskCancel := context.WithCancel(context.Background())
+	r.taskCancel = taskCancel
+	r.taskWg.Add(1)
+	go r.taskHandler(taskCtx, route)
+}
+
+func (r *ctrlRouter) isDraining() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.draining
+}
+
+// Hands a task to a Controller. If Drain gives up first, the task is
+// returned as abandoned instead.
+func (r *ctrlRouter) handoff(ctrl *Controller, task measurement.Task) {
+	select {
+	case ctrl.TaskQueue() <- task:
+	case <-r.sendCtx.Done():
+		r.abandoned++
+		r.fail(task, measurement.TASK_ABANDONED, ErrTaskAbandoned)
+	}
+}
+
+// Returns a task that was never handed to a Controller
+func (r *ctrlRouter) fail(task measurement.Task,
+	status measurement.TaskStatus, err error) {
+	task.Status = status
+	task.Error = err.Error()
+	r.resQ <- task
+}
+
+// Stops accepting new tasks, hands off any that are queued (or
+// abandons them if ctx is done first), and drains each Controller.
+// See Controller.Drain.
+func (r *ctrlRouter) drain(ctx context.Context) error {
+	r.mu.Lock()
+	r.draining = true
+	r.mu.Unlock()
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.sendCancel()
+		case <-stop:
+		}
+	}()
+	r.taskCancel()
+	r.taskWg.Wait()
+	close(stop)
+
+	errs := make(chan error, len(r.ctrls))
+	for _, ctrl := range r.ctrls {
+		go func(ctrl *Controller) {
+			errs <- ctrl.Drain(ctx)
+		}(ctrl)
+	}
+	var err error
+	for range r.ctrls {
+		if e := <-errs; e != nil {
+			err = e
+		}
+	}
+	if err == nil && r.abandoned > 0 {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (r *ctrlRouter) close() {
+	for _, ctrl := range r.ctrls {
+		ctrl.Close()
+	}
+	r.resWg.Wait()
+	r.sendCancel()
+	r.log.Debug().Msgf("Shutdown complete")
+}
+
+func (r *ctrlRouter) taskHandler(ctx context.Context,
+	route func(task measurement.Task)) {
+	defer func() {
+		close(r.taskQ)
+		r.resWg.Done()
+		r.taskWg.Done()
+	}()
+
+hamster:
+	for {
+		select {
+		case task := <-r.taskQ:
+			// this might block until Drain gives up
+			route(task)
+
+		case <-ctx.Done():
+			// canceled, need to drain taskQ and then exit
+			break hamster
+		}
+	}
+
+	for len(r.taskQ) > 0 {
+		route(<-r.taskQ)
+	}
+}
+
+func (r *ctrlRouter) resultForwarder(ctrl *Controller) {
+	defer r.resWg.Done()
+	for task := range ctrl.ResultQueue() {
+		r.resQ <- task
+	}
+}
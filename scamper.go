@@ -0,0 +1,300 @@
+package scurry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Defaults for ScamperProcessConfig
+const (
+	SCAMPER_BIN             = "scamper"
+	SCAMPER_READY_TIMEOUT   = time.Second * 10
+	SCAMPER_RESTART_BACKOFF = time.Second
+	SCAMPER_STOP_TIMEOUT    = time.Second * 5
+)
+
+type ScamperProcessConfig struct {
+	// Path to the scamper binary (default SCAMPER_BIN, which is
+	// looked up in PATH)
+	Binary string
+	// Packets per second that scamper may send (scamper -p). Zero
+	// uses scamper's default.
+	PPS int
+	// Maximum number of tasks that scamper may probe concurrently
+	// (scamper -w). Zero uses scamper's default.
+	Window int
+	// Additional arguments to pass to scamper
+	Args []string
+
+	// How long to wait for scamper's control socket to become
+	// ready (default SCAMPER_READY_TIMEOUT)
+	ReadyTimeout time.Duration
+	// Don't restart scamper if it exits
+	DisableRestart bool
+	// How long to wait before restarting scamper (default
+	// SCAMPER_RESTART_BACKOFF)
+	RestartBackoff time.Duration
+}
+
+// Checks that the config is usable. Zero values are replaced with
+// defaults.
+func (cfg ScamperProcessConfig) Validate() error {
+	if cfg.PPS < 0 {
+		return fmt.Errorf("scamper pps must not be negative")
+	}
+	if cfg.Window < 0 {
+		return fmt.Errorf("scamper window must not be negative")
+	}
+	if cfg.ReadyTimeout < 0 || cfg.RestartBackoff < 0 {
+		return fmt.Errorf("scamper timeouts must not be negative")
+	}
+	return nil
+}
+
+func (cfg ScamperProcessConfig) withDefaults() ScamperProcessConfig {
+	if cfg.Binary == "" {
+		cfg.Binary = SCAMPER_BIN
+	}
+	if cfg.ReadyTimeout == 0 {
+		cfg.ReadyTimeout = SCAMPER_READY_TIMEOUT
+	}
+	if cfg.RestartBackoff == 0 {
+		cfg.RestartBackoff = SCAMPER_RESTART_BACKOFF
+	}
+	return cfg
+}
+
+// A scamper process managed by scurry. Scamper is run in the
+// foreground with its control socket on a temporary unix domain
+// socket (see URL), and is restarted if it exits. Its output is
+// logged.
+type ScamperProcess struct {
+	log  Logger
+	cfg  ScamperProcessConfig
+	dir  string // temporary directory holding the socket
+	sock string
+
+	mu     *sync.Mutex // protects cmd and exited
+	cmd    *exec.Cmd
+	exited chan struct{} // closed when cmd exits
+
+	superCancel context.CancelFunc
+	superWg     *sync.WaitGroup
+}
+
+// Starts scamper, and waits until its control socket is ready
+func NewScamperProcess(log Logger, cfg ScamperProcessConfig) (*ScamperProcess, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	dir, err := os.MkdirTemp("", "scurry-")
+	if err != nil {
+		return nil, err
+	}
+
+	superCtx, superCancel := context.WithCancel(context.Background())
+	p := &ScamperProcess{
+		log:  initLogger(log, "scamper"),
+		cfg:  cfg,
+		dir:  dir,
+		sock: filepath.Join(dir, "scamper.sock"),
+		mu:   &sync.Mutex{},
+
+		superCancel: superCancel,
+		superWg:     &sync.WaitGroup{},
+	}
+
+	if err := p.start(); err != nil {
+		superCancel()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	// keep an eye on it
+	p.superWg.Add(1)
+	go p.supervisor(superCtx)
+
+	return p, nil
+}
+
+// The URL of scamper's control socket, for use in ScAttachConfig
+func (p *ScamperProcess) URL() string {
+	return p.sock
+}
+
+// Stops scamper (and does not restart it)
+func (p *ScamperProcess) Close() {
+	p.superCancel()
+	p.superWg.Wait()
+	p.stop()
+	os.RemoveAll(p.dir)
+	p.log.Debug().Msgf("Scamper stopped")
+}
+
+// private methods
+
+func (p *ScamperProcess) args() []string {
+	args := []string{"-U", p.sock}
+	if p.cfg.PPS > 0 {
+		args = append(args, "-p", strconv.Itoa(p.cfg.PPS))
+	}
+	if p.cfg.Window > 0 {
+		args = append(args, "-w", strconv.Itoa(p.cfg.Window))
+	}
+	return append(args, p.cfg.Args...)
+}
+
+// Launches scamper and waits for its control socket to be ready
+func (p *ScamperProcess) start() error {
+	// scamper won't start if a stale socket is lying around
+	os.Remove(p.sock)
+
+	cmd := exec.Command(p.cfg.Binary, p.args()...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	p.log.Info().
+		Str("binary", p.cfg.Binary).
+		Strs("args", cmd.Args[1:]).
+		Msgf("Starting scamper")
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go p.logOutput(stdout, "stdout")
+	go p.logOutput(stderr, "stderr")
+
+	exited := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		p.log.Debug().
+			AnErr("reason", err).
+			Msgf("Scamper exited")
+		close(exited)
+	}()
+
+	p.mu.Lock()
+	p.cmd = cmd
+	p.exited = exited
+	p.mu.Unlock()
+
+	if err := p.waitReady(exited); err != nil {
+		p.stop()
+		return err
+	}
+	p.log.Info().
+		Int("pid", cmd.Process.Pid).
+		Str("socket", p.sock).
+		Msgf("Scamper ready")
+	return nil
+}
+
+// Polls scamper's control socket until it accepts a connection
+func (p *ScamperProcess) waitReady(exited chan struct{}) error {
+	timeout := time.NewTimer(p.cfg.ReadyTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(time.Millisecond * 50)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			conn, err := net.Dial("unix", p.sock)
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+
+		case <-exited:
+			return fmt.Errorf("scamper exited before its control socket was ready")
+
+		case <-timeout.C:
+			return fmt.Errorf("timed out waiting for scamper control socket")
+		}
+	}
+}
+
+// Stops scamper, killing it if it doesn't exit promptly
+func (p *ScamperProcess) stop() {
+	p.mu.Lock()
+	cmd, exited := p.cmd, p.exited
+	p.mu.Unlock()
+	if cmd == nil {
+		return
+	}
+	cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-exited:
+	case <-time.After(SCAMPER_STOP_TIMEOUT):
+		p.log.Warn().Msgf("Scamper did not exit, killing it")
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func (p *ScamperProcess) logOutput(r io.Reader, stream string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.log.Info().
+			Str("stream", stream).
+			Msgf("%s", scanner.Text())
+	}
+}
+
+// Restarts scamper if it exits
+func (p *ScamperProcess) supervisor(ctx context.Context) {
+	defer p.superWg.Done()
+
+	for {
+		p.mu.Lock()
+		exited := p.exited
+		p.mu.Unlock()
+
+		select {
+		case <-exited:
+			// uh oh
+
+		case <-ctx.Done():
+			return
+		}
+
+		if p.cfg.DisableRestart {
+			p.log.Error().Msgf("Scamper exited unexpectedly")
+			return
+		}
+		p.log.Error().
+			Dur("backoff", p.cfg.RestartBackoff).
+			Msgf("Scamper exited unexpectedly, restarting")
+
+		// keep trying until we succeed (or are closed)
+		for {
+			select {
+			case <-time.After(p.cfg.RestartBackoff):
+			case <-ctx.Done():
+				return
+			}
+			err := p.start()
+			if err == nil {
+				break
+			}
+			p.log.Error().
+				Err(err).
+				Msgf("Failed to restart scamper")
+		}
+	}
+}
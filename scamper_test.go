@@ -0,0 +1,180 @@
+package scurry
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// If set, the test binary acts as a fake scamper executable instead
+// of running the tests. The value selects how it behaves: "serve"
+// serves a fakeScamper on the -U socket, "exit" exits straight away,
+// and "hang" never opens the socket.
+const fakeScamperEnv = "SCURRY_FAKE_SCAMPER"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(fakeScamperEnv); mode != "" {
+		runFakeScamper(mode)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// Pretends to be scamper. The arguments it was run with are written
+// next to its socket so that tests can check them.
+func runFakeScamper(mode string) {
+	// we only care about -U, which always comes first
+	fs := flag.NewFlagSet("scamper", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	sock := fs.String("U", "", "control socket")
+	fs.Parse(os.Args[1:])
+	os.WriteFile(filepath.Join(filepath.Dir(*sock), "args"),
+		[]byte(strings.Join(os.Args[1:], " ")), 0644)
+
+	switch mode {
+	case "exit":
+		os.Exit(1)
+	case "hang":
+		select {}
+	}
+	ln, err := net.Listen("unix", *sock)
+	if err != nil {
+		os.Exit(1)
+	}
+	f := &fakeScamper{}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+		go f.serve(conn)
+	}
+}
+
+func fakeScamperConfig(t *testing.T, mode string) ScamperProcessConfig {
+	os.Setenv(fakeScamperEnv, mode)
+	t.Cleanup(func() {
+		os.Unsetenv(fakeScamperEnv)
+	})
+	return ScamperProcessConfig{
+		Binary:         os.Args[0],
+		PPS:            100,
+		Window:         5,
+		Args:           []string{"-O", "planetlab"},
+		ReadyTimeout:   5 * time.Second,
+		RestartBackoff: 10 * time.Millisecond,
+	}
+}
+
+func (p *ScamperProcess) process() *os.Process {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cmd.Process
+}
+
+func TestScamperProcess(t *testing.T) {
+	p, err := NewScamperProcess(zerolog.Nop(), fakeScamperConfig(t, "serve"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	args, err := os.ReadFile(filepath.Join(p.dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-U " + p.URL() + " -p 100 -w 5 -O planetlab"
+	if string(args) != want {
+		t.Errorf("scamper args = %q, want %q", args, want)
+	}
+
+	// scamper is ready as soon as we're back
+	conn, err := net.Dial("unix", p.URL())
+	if err != nil {
+		t.Fatalf("control socket not ready: %v", err)
+	}
+	conn.Close()
+
+	// if scamper dies, it is restarted
+	pid := p.process().Pid
+	p.process().Kill()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.process().Pid == pid {
+		if time.Now().After(deadline) {
+			t.Fatal("scamper was not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// start only swaps in the new process once it has been
+	// started, but it may not be ready yet
+	for {
+		conn, err := net.Dial("unix", p.URL())
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("restarted scamper not ready: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// and closing stops it for good
+	p.mu.Lock()
+	exited := p.exited
+	p.mu.Unlock()
+	p.Close()
+	select {
+	case <-exited:
+	default:
+		t.Errorf("scamper still running after Close")
+	}
+	if _, err := os.Stat(p.dir); !os.IsNotExist(err) {
+		t.Errorf("socket directory not removed: %v", err)
+	}
+}
+
+func TestScamperProcessStartFailure(t *testing.T) {
+	cfg := fakeScamperConfig(t, "exit")
+	if _, err := NewScamperProcess(zerolog.Nop(), cfg); err == nil ||
+		!strings.Contains(err.Error(), "exited") {
+		t.Errorf("NewScamperProcess() = %v, want an exit error", err)
+	}
+
+	cfg = fakeScamperConfig(t, "hang")
+	cfg.ReadyTimeout = 200 * time.Millisecond
+	if _, err := NewScamperProcess(zerolog.Nop(), cfg); err == nil ||
+		!strings.Contains(err.Error(), "timed out") {
+		t.Errorf("NewScamperProcess() = %v, want a timeout", err)
+	}
+}
+
+// A Controller can run its own scamper, and close it
+func TestControllerManagedScamper(t *testing.T) {
+	cfg := fakeScamperConfig(t, "serve")
+	ctrl := newTestController(t, ControllerConfig{Scamper: &cfg})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := ctrl.Do(ctx, pingTask("192.0.2.1"))
+	if err != nil || res == nil {
+		t.Fatalf("Do() = %v, %v", res, err)
+	}
+	if res.ResultUserId() == 0 {
+		t.Errorf("result has no UserId")
+	}
+
+	ctrl.Drain(ctx)
+	ctrl.Close()
+	if _, err := os.Stat(ctrl.scamper.dir); !os.IsNotExist(err) {
+		t.Errorf("scamper not closed: %v", err)
+	}
+}
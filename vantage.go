@@ -0,0 +1,286 @@
+package scurry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alistairking/scurry/measurement"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrUnknownVantage = errors.New("unknown vantage point")
+)
+
+// A named scamper endpoint
+type VantagePointConfig struct {
+	// Name used to select the vantage point (see Task.Vantages),
+	// and to tag its results (see Task.Vantage)
+	Name string
+	// Configuration for the Controller that drives the vantage
+	// point's scamper
+	Controller ControllerConfig
+}
+
+type MultiControllerConfig struct {
+	VantagePoints []VantagePointConfig
+
+	// Number of tasks that may be queued on the TaskQueue
+	// (default SEND_Q_LEN)
+	TaskQueueLen int
+	// Number of finished tasks that may be queued on the
+	// ResultQueue (default RECV_Q_LEN)
+	ResultQueueLen int
+}
+
+// Checks that the config is usable
+func (cfg MultiControllerConfig) Validate() error {
+	if len(cfg.VantagePoints) == 0 {
+		return fmt.Errorf("at least one vantage point is required")
+	}
+	if cfg.TaskQueueLen < 0 {
+		return fmt.Errorf("task queue length must not be negative")
+	}
+	if cfg.ResultQueueLen < 0 {
+		return fmt.Errorf("result queue length must not be negative")
+	}
+	names := map[string]bool{}
+	for i, vp := range cfg.VantagePoints {
+		if vp.Name == "" {
+			return fmt.Errorf("vantage point %d has no name", i)
+		}
+		if names[vp.Name] {
+			return fmt.Errorf("duplicate vantage point name: %s", vp.Name)
+		}
+		names[vp.Name] = true
+		if err := vp.Controller.Validate(); err != nil {
+			return fmt.Errorf("vantage point %s: %v", vp.Name, err)
+		}
+	}
+	return nil
+}
+
+func (cfg MultiControllerConfig) withDefaults() MultiControllerConfig {
+	if cfg.TaskQueueLen == 0 {
+		cfg.TaskQueueLen = SEND_Q_LEN
+	}
+	if cfg.ResultQueueLen == 0 {
+		cfg.ResultQueueLen = RECV_Q_LEN
+	}
+	return cfg
+}
+
+// Parses a vantage point given as "name=url" (e.g., on the command
+// line). If no name is given, the URL is used as the name.
+func ParseVantagePoint(s string) VantagePointConfig {
+	name, url := s, s
+	if i := strings.Index(s, "="); i >= 0 {
+		name, url = s[:i], s[i+1:]
+	}
+	return VantagePointConfig{
+		Name:       name,
+		Controller: ControllerConfig{ScamperURL: url},
+	}
+}
+
+// Runs tasks on several named vantage points (each a scamper instance
+// driven by its own Controller). Each task is run on the vantage
+// points listed in its Vantages field (or on all of them), and one
+// result is returned per vantage point, with its Vantage field set.
+// Each Controller assigns UserIds independently, so UserIds are only
+// unique per vantage point.
+type MultiController struct {
+	log   Logger
+	cfg   MultiControllerConfig
+	names []string // in config order
+	ctrls map[string]*Controller
+
+	router *ctrlRouter
+}
+
+func NewMultiController(log zerolog.Logger,
+	cfg MultiControllerConfig) (*MultiController, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+
+	m := &MultiController{
+		log:   initLogger(log, "multi-controller"),
+		cfg:   cfg,
+		ctrls: map[string]*Controller{},
+	}
+
+	for _, vp := range cfg.VantagePoints {
+		ctrl, err := NewController(log.With().
+			Str("vantage", vp.Name).
+			Logger(), vp.Controller)
+		if err != nil {
+			for _, c := range m.ctrls {
+				c.Drain(context.Background())
+				c.Close()
+			}
+			return nil, fmt.Errorf("vantage point %s: %v", vp.Name, err)
+		}
+		m.names = append(m.names, vp.Name)
+		m.ctrls[vp.Name] = ctrl
+	}
+
+	// merge the results from each vantage point, and start fanning
+	// out tasks
+	ctrls := make([]*Controller, 0, len(m.names))
+	for _, name := range m.names {
+		ctrls = append(ctrls, m.ctrls[name])
+	}
+	m.router = newCtrlRouter(m.log, ctrls, cfg.TaskQueueLen,
+		cfg.ResultQueueLen)
+	m.router.start(m.fanOut)
+
+	m.log.Debug().
+		Strs("vantage-points", m.names).
+		Msgf("MultiController online")
+
+	return m, nil
+}
+
+func (m *MultiController) TaskQueue() chan measurement.Task {
+	return m.router.taskQ
+}
+
+func (m *MultiController) ResultQueue() chan measurement.Task {
+	return m.router.resQ
+}
+
+// The names of the vantage points, in the order they were configured
+func (m *MultiController) VantagePoints() []string {
+	return m.names
+}
+
+// The Controller for the named vantage point, or nil if there is no
+// such vantage point
+func (m *MultiController) Controller(name string) *Controller {
+	return m.ctrls[name]
+}
+
+// Submits a task to each of its vantage points, and returns a handle
+// for each (in the order of Task.Vantages, or of the configured
+// vantage points). See Controller.Submit. If any of the vantage
+// points is unknown, no tasks are submitted and ErrUnknownVantage is
+// returned.
+func (m *MultiController) Submit(task measurement.Task) ([]*TaskHandle, error) {
+	return m.SubmitContext(context.Background(), task)
+}
+
+// Like Submit, but the tasks are cancelled if ctx is done before they
+// finish.
+func (m *MultiController) SubmitContext(ctx context.Context,
+	task measurement.Task) ([]*TaskHandle, error) {
+	vantages, err := m.vantagesFor(task)
+	if err != nil {
+		return nil, err
+	}
+	draining := m.isDraining()
+	handles := make([]*TaskHandle, 0, len(vantages))
+	for _, vantage := range vantages {
+		ctrl := m.ctrls[vantage]
+		task.Vantage = vantage
+		if draining {
+			handles = append(handles, ctrl.rejectTask(task))
+			continue
+		}
+		handles = append(handles, ctrl.SubmitContext(ctx, task))
+	}
+	return handles, nil
+}
+
+// Submits a task to each of its vantage points, and blocks until all
+// have finished, or until ctx is done. The finished tasks are
+// returned in the same order as by Submit, with their Status (and
+// Error) set.
+func (m *MultiController) Do(ctx context.Context,
+	task measurement.Task) ([]measurement.Task, error) {
+	if m.isDraining() {
+		return nil, ErrControllerDraining
+	}
+	handles, err := m.SubmitContext(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	// the handles are cancelled (and so finish) if ctx is done
+	tasks := make([]measurement.Task, 0, len(handles))
+	for _, h := range handles {
+		<-h.Done()
+		tasks = append(tasks, h.Task())
+	}
+	return tasks, ctx.Err()
+}
+
+func (m *MultiController) Outstanding() int {
+	total := 0
+	for _, ctrl := range m.ctrls {
+		total += ctrl.Outstanding()
+	}
+	return total
+}
+
+// Number of tasks that are being held back because their target is
+// busy. See Controller.Waiting.
+func (m *MultiController) Waiting() int {
+	total := 0
+	for _, ctrl := range m.ctrls {
+		total += ctrl.Waiting()
+	}
+	return total
+}
+
+// Stops accepting new tasks, and drains each vantage point's
+// Controller. Queued tasks that can't be handed to a vantage point
+// before ctx is done are returned as abandoned. See Controller.Drain.
+func (m *MultiController) Drain(ctx context.Context) error {
+	return m.router.drain(ctx)
+}
+
+func (m *MultiController) Close() {
+	if m == nil {
+		return
+	}
+	m.router.close()
+}
+
+// private methods
+
+func (m *MultiController) isDraining() bool {
+	return m.router.isDraining()
+}
+
+// Returns the vantage points that the task should be run on
+func (m *MultiController) vantagesFor(task measurement.Task) ([]string, error) {
+	if len(task.Vantages) == 0 {
+		return m.names, nil
+	}
+	for _, vantage := range task.Vantages {
+		if _, exists := m.ctrls[vantage]; !exists {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVantage, vantage)
+		}
+	}
+	return task.Vantages, nil
+}
+
+// Hands a copy of the task to each of its vantage points
+func (m *MultiController) fanOut(task measurement.Task) {
+	vantages, err := m.vantagesFor(task)
+	if err != nil {
+		m.log.Warn().
+			Err(err).
+			Str("target", task.Target).
+			Msgf("Rejecting task")
+		m.router.fail(task, measurement.TASK_REJECTED, err)
+		return
+	}
+	for _, vantage := range vantages {
+		task.Vantage = vantage
+		m.router.handoff(m.ctrls[vantage], task)
+	}
+}
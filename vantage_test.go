@@ -0,0 +1,121 @@
+package scurry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alistairking/scurry/measurement"
+	"github.com/rs/zerolog"
+)
+
+func newTestMultiController(t *testing.T,
+	cfg MultiControllerConfig) *MultiController {
+	t.Helper()
+	m, err := NewMultiController(zerolog.Nop(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMultiController(t *testing.T) {
+	m := newTestMultiController(t, MultiControllerConfig{
+		VantagePoints: []VantagePointConfig{
+			{Name: "vp1", Controller: ControllerConfig{
+				ScamperURL: (&fakeScamper{}).listen(t)}},
+			{Name: "vp2", Controller: ControllerConfig{
+				ScamperURL: (&fakeScamper{}).listen(t)}},
+		},
+	})
+	results := collectQueue(m.ResultQueue())
+
+	m.TaskQueue() <- pingTask("192.0.2.1")
+	only := pingTask("192.0.2.2")
+	only.Vantages = []string{"vp2"}
+	m.TaskQueue() <- only
+	unknown := pingTask("192.0.2.3")
+	unknown.Vantages = []string{"vp3"}
+	m.TaskQueue() <- unknown
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Drain(ctx); err != nil {
+		t.Fatalf("Drain() = %v", err)
+	}
+	m.Close()
+
+	got := map[string]measurement.TaskStatus{}
+	for _, task := range <-results {
+		got[task.Target+"@"+task.Vantage] = task.Status
+	}
+	want := map[string]measurement.TaskStatus{
+		"192.0.2.1@vp1": measurement.TASK_COMPLETED,
+		"192.0.2.1@vp2": measurement.TASK_COMPLETED,
+		"192.0.2.2@vp2": measurement.TASK_COMPLETED,
+		"192.0.2.3@":    measurement.TASK_REJECTED,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+// One stuck vantage point must not stop Drain from giving up once its
+// context is done
+func TestMultiControllerDrainWithStuckScamper(t *testing.T) {
+	m := newTestMultiController(t, MultiControllerConfig{
+		VantagePoints: []VantagePointConfig{
+			{Name: "ok", Controller: ControllerConfig{
+				ScamperURL: (&fakeScamper{}).listen(t)}},
+			{Name: "stuck", Controller: ControllerConfig{
+				ScamperURL:   (&fakeScamper{stuck: true}).listen(t),
+				Attach:       ScAttachConfig{CommandQueueLen: 1},
+				TaskQueueLen: 1,
+			}},
+		},
+	})
+	results := collectQueue(m.ResultQueue())
+
+	for i := 1; i <= 10; i++ {
+		m.TaskQueue() <- pingTask(fmt.Sprintf("192.0.2.%d", i))
+	}
+	// wait until the stuck vantage point has backed up into the
+	// task handler (see TestPoolDrainWithStuckScamper)
+	stuck := m.Controller("stuck")
+	for stuck.Outstanding() < 3 || len(stuck.TaskQueue()) < 1 ||
+		len(m.TaskQueue()) > 5 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		300*time.Millisecond)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() {
+		drained <- m.Drain(ctx)
+	}()
+	select {
+	case err := <-drained:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Drain() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Drain() ignored its context")
+	}
+	m.Close()
+
+	tasks := <-results
+	if len(tasks) != 20 {
+		t.Fatalf("got %d results, want 20", len(tasks))
+	}
+	for _, task := range tasks {
+		// tasks may be given up on before they reach the healthy
+		// vantage point, but none can have run on the stuck one
+		if task.Status != measurement.TASK_ABANDONED &&
+			(task.Vantage == "stuck" ||
+				task.Status != measurement.TASK_COMPLETED) {
+			t.Errorf("task towards %s at %s: status %s",
+				task.Target, task.Vantage, task.Status)
+		}
+	}
+}